@@ -0,0 +1,203 @@
+package demsphere
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Accuracy errors are binned logarithmically from accuracyMinError meters,
+// so that percentiles are accurate to a few percent without keeping every
+// sample.
+const (
+	accuracyMinError      = 1e-3
+	accuracyBinsPerDecade = 100
+	accuracyDecades       = 10
+)
+
+// Accuracy measures how far a triangulated mesh deviates vertically from
+// the surface that it approximates, in meters before exaggeration.
+type Accuracy struct {
+	Samples int
+	Max     float64
+	Mean    float64
+	RMS     float64
+
+	// Skipped counts triangles that the Triangulator did not produce, such
+	// as the walls and base of a CapSolid.
+	Skipped int
+
+	histogram []int
+	heat      []float64
+	w, h      int
+}
+
+// Accuracy samples the source densely over each triangle of a mesh returned
+// by the last call to Triangulate, subdividing each triangle until its
+// samples are as close as the source's pixels or maxDepth times, and
+// accumulates the vertical errors. The mesh's vertices, which lie on the
+// surface, are left out, and points on an edge are measured once, for the
+// first triangle that shares it. Errors are also gathered on an
+// equirectangular grid heatmapWidth pixels wide for Heatmap.
+func (tri *Triangulator) Accuracy(triangles []Triangle, maxDepth, heatmapWidth int) *Accuracy {
+	keys := make(map[Vector]Vector, len(tri.points))
+	for k, p := range tri.points {
+		keys[p] = k
+	}
+	resolution := SourceResolution(tri.source)
+	w, h := max(heatmapWidth, 1), max(heatmapWidth/2, 1)
+	a := &Accuracy{
+		histogram: make([]int, accuracyBinsPerDecade*accuracyDecades+1),
+		heat:      make([]float64, w*h),
+		w:         w,
+		h:         h,
+	}
+	for i := range a.heat {
+		a.heat[i] = -1
+	}
+	k := 1 / (tri.exaggeration * tri.scale)
+	var sum, squares float64
+	seen := make(map[Vector]bool)
+	for _, t := range triangles {
+		v1, ok1 := keys[t.A]
+		v2, ok2 := keys[t.B]
+		v3, ok3 := keys[t.C]
+		if !ok1 || !ok2 || !ok3 {
+			a.Skipped++
+			continue
+		}
+		depth := maxDepth
+		if resolution > 0 {
+			d := tri.domain
+			size := math.Max(angle(d.direction(v1), d.direction(v2)),
+				math.Max(angle(d.direction(v2), d.direction(v3)), angle(d.direction(v3), d.direction(v1))))
+			depth = min(max(int(math.Ceil(math.Log2(size/resolution))), 1), maxDepth)
+		}
+		plane := MakePlane(t.A, t.B, t.C)
+		var sample func(depth int, v1, v2, v3 Vector)
+		sample = func(depth int, v1, v2, v3 Vector) {
+			if depth > 0 {
+				v12 := tri.domain.sampleMidpoint(v1, v2)
+				v23 := tri.domain.sampleMidpoint(v2, v3)
+				v31 := tri.domain.sampleMidpoint(v3, v1)
+				sample(depth-1, v1, v12, v31)
+				sample(depth-1, v2, v23, v12)
+				sample(depth-1, v3, v31, v23)
+				sample(depth-1, v12, v23, v31)
+				return
+			}
+			for _, v := range [3]Vector{v1, v2, v3} {
+				if _, ok := tri.points[v]; ok || seen[v] {
+					continue
+				}
+				seen[v] = true
+				direction := tri.domain.direction(v)
+				e := tri.source.Elevation(direction, 0) * tri.exaggeration
+				p := tri.domain.point(v, e).MulScalar(tri.scale)
+				up := tri.domain.point(v, e+1).MulScalar(tri.scale).Sub(p).Normalize()
+				d := plane.DistanceToPoint(p) / math.Abs(plane.N.Dot(up)) * k
+				if math.IsNaN(d) || math.IsInf(d, 0) {
+					continue
+				}
+				a.add(d, direction)
+				sum += d
+				squares += d * d
+			}
+		}
+		sample(depth, v1, v2, v3)
+		tri.domain.forgetSamples()
+	}
+	if a.Samples > 0 {
+		a.Mean = sum / float64(a.Samples)
+		a.RMS = math.Sqrt(squares / float64(a.Samples))
+	}
+	return a
+}
+
+func (a *Accuracy) add(d float64, direction Vector) {
+	a.Samples++
+	a.Max = math.Max(a.Max, d)
+	bin := 0
+	if d > accuracyMinError {
+		bin = min(int(math.Log10(d/accuracyMinError)*accuracyBinsPerDecade)+1, len(a.histogram)-1)
+	}
+	a.histogram[bin]++
+	u, v := GlobalEquirectangular.Project(direction)
+	x := min(max(int(u*float64(a.w)), 0), a.w-1)
+	y := min(max(int(v*float64(a.h)), 0), a.h-1)
+	i := x + y*a.w
+	a.heat[i] = math.Max(a.heat[i], d)
+}
+
+// Percentile returns the error below which p percent of the samples lie.
+func (a *Accuracy) Percentile(p float64) float64 {
+	if a.Samples == 0 {
+		return 0
+	}
+	target := clamp(p/100, 0, 1) * float64(a.Samples)
+	var count float64
+	for bin, n := range a.histogram {
+		count += float64(n)
+		if count >= target {
+			if bin == 0 {
+				return math.Min(accuracyMinError, a.Max)
+			}
+			upper := accuracyMinError * math.Pow(10, float64(bin)/accuracyBinsPerDecade)
+			return math.Min(upper, a.Max)
+		}
+	}
+	return a.Max
+}
+
+// Heatmap renders the largest error in each cell of the equirectangular
+// grid, from dark for no error to bright for errors of limit meters or
+// more. A limit of zero uses Max. Cells without samples take the largest
+// error of their neighbors, or are transparent if they have none.
+func (a *Accuracy) Heatmap(limit float64) image.Image {
+	if limit <= 0 {
+		limit = a.Max
+	}
+	im := image.NewNRGBA(image.Rect(0, 0, a.w, a.h))
+	for y := 0; y < a.h; y++ {
+		for x := 0; x < a.w; x++ {
+			d := a.heat[x+y*a.w]
+			if d < 0 {
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						nx, ny := (x+dx+a.w)%a.w, y+dy
+						if ny >= 0 && ny < a.h {
+							d = math.Max(d, a.heat[nx+ny*a.w])
+						}
+					}
+				}
+			}
+			if d < 0 {
+				continue
+			}
+			t := 0.0
+			if limit > 0 {
+				t = clamp(d/limit, 0, 1)
+			}
+			im.SetNRGBA(x, y, heatColor(t))
+		}
+	}
+	return im
+}
+
+// heatStops is a black, purple, orange, yellow color ramp.
+var heatStops = [][3]float64{
+	{0, 0, 4}, {87, 16, 110}, {188, 55, 84}, {249, 142, 9}, {252, 255, 164},
+}
+
+func heatColor(t float64) color.NRGBA {
+	x := t * float64(len(heatStops)-1)
+	i := min(int(x), len(heatStops)-2)
+	f := x - float64(i)
+	a, b := heatStops[i], heatStops[i+1]
+	return color.NRGBA{
+		R: uint8(lerp(a[0], b[0], f)),
+		G: uint8(lerp(a[1], b[1], f)),
+		B: uint8(lerp(a[2], b[2], f)),
+		A: 255,
+	}
+}
@@ -0,0 +1,25 @@
+package demsphere
+
+import "testing"
+
+// On a mesh far coarser than its source, most samples are off the surface,
+// and each point of the sampling grid is measured once.
+func TestAccuracyCoarseMesh(t *testing.T) {
+	tri := NewSourceTriangulator(testRegionSource(), 2, 2, 6371000, 100, 1, 1/6371000.0)
+	triangles := tri.Triangulate()
+	a := tri.Accuracy(triangles, 2, 16)
+	if a.Skipped != 0 {
+		t.Errorf("skipped %d triangles", a.Skipped)
+	}
+	// the mesh's 320 triangles split twice have 2562 vertices, of which
+	// the mesh's own 162 are left out
+	if a.Samples != 2400 {
+		t.Errorf("got %d samples, want 2400", a.Samples)
+	}
+	if p := a.Percentile(50); p < 100 {
+		t.Errorf("got a median error of %g m on a mesh of hills 5000 m high", p)
+	}
+	if a.Max < a.Percentile(90) || a.RMS < a.Mean || a.Mean <= 0 {
+		t.Errorf("inconsistent summary: %+v", a)
+	}
+}
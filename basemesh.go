@@ -0,0 +1,129 @@
+package demsphere
+
+import (
+	"maps"
+	"math"
+)
+
+// NewMeshTriangulator returns a Triangulator that starts from an arbitrary
+// closed, outward-facing base mesh instead of an icosahedron, such as a
+// low-resolution shape model of a body that is not star-shaped. Its
+// triangles are subdivided adaptively and each point is displaced along the
+// normal interpolated from the base mesh's vertex normals by the source's
+// elevation, sampled in the direction of the point on the base mesh from the
+// origin. The source itself is therefore a function of direction: where a
+// ray from the origin crosses the base mesh more than once, every crossing
+// gets the same elevation, so the base mesh should carry the body's shape
+// and the source only the relief that a direction can tell apart. The base
+// mesh is in meters and Region and Ellipsoid are ignored.
+func NewMeshTriangulator(base []Triangle, source ElevationSource, minDetail, maxDetail int, tolerance, exaggeration, scale float64) *Triangulator {
+	tri := NewSourceTriangulator(source, minDetail, maxDetail, 0, tolerance, exaggeration, scale)
+	tri.domain = newMeshDomain(base)
+	return tri
+}
+
+// meshDomain is keyed by points on the faces of a base mesh. The normal at
+// each key is interpolated linearly across the faces, so it only depends on
+// the edge or face that the key lies on. The interpolated normals of the
+// triangles' vertices are recorded as midpoints are made, starting again
+// from the base mesh's vertex normals for each triangulation. Points that
+// are only sampled keep theirs in samples until forgetSamples is called, so
+// that the number of recorded normals follows the number of vertices.
+type meshDomain struct {
+	base          []Triangle
+	vertexNormals map[Vector]Vector
+	normals       map[Vector]Vector
+	samples       map[Vector]Vector
+	size          float64
+}
+
+func newMeshDomain(base []Triangle) *meshDomain {
+	normals := make(map[Vector]Vector)
+	var edges, radius float64
+	for _, t := range base {
+		n := t.B.Sub(t.A).Cross(t.C.Sub(t.A))
+		for _, v := range [3]Vector{t.A, t.B, t.C} {
+			normals[v] = normals[v].Add(n)
+			radius += v.Length()
+		}
+		edges += t.A.Sub(t.B).Length() + t.B.Sub(t.C).Length() + t.C.Sub(t.A).Length()
+	}
+	for v, n := range normals {
+		normals[v] = n.Normalize()
+	}
+	size := 0.0
+	if radius > 0 {
+		size = edges / radius
+	}
+	return &meshDomain{base, normals, maps.Clone(normals), make(map[Vector]Vector), size}
+}
+
+func (m *meshDomain) reset() {
+	m.normals = maps.Clone(m.vertexNormals)
+	m.samples = make(map[Vector]Vector)
+}
+
+// forgetSamples drops the normals recorded by sampleMidpoint.
+func (m *meshDomain) forgetSamples() {
+	clear(m.samples)
+}
+
+func (m *meshDomain) seed() []Triangle {
+	return m.base
+}
+
+func (m *meshDomain) quads() [][4]Vector {
+	return nil
+}
+
+func (m *meshDomain) midpoint(a, b Vector) Vector {
+	v := Vector{(a.X + b.X) / 2, (a.Y + b.Y) / 2, (a.Z + b.Z) / 2}
+	if _, ok := m.normals[v]; !ok {
+		m.normals[v] = m.interpolate(a, b)
+	}
+	return v
+}
+
+// sampleMidpoint is midpoint for a point that is only sampled.
+func (m *meshDomain) sampleMidpoint(a, b Vector) Vector {
+	v := Vector{(a.X + b.X) / 2, (a.Y + b.Y) / 2, (a.Z + b.Z) / 2}
+	if _, ok := m.normals[v]; ok {
+		return v
+	}
+	if _, ok := m.samples[v]; !ok {
+		m.samples[v] = m.interpolate(a, b)
+	}
+	return v
+}
+
+// interpolate returns the normal halfway between the normals of two keys.
+func (m *meshDomain) interpolate(a, b Vector) Vector {
+	na, nb := m.normal(a), m.normal(b)
+	return Vector{(na.X + nb.X) / 2, (na.Y + nb.Y) / 2, (na.Z + nb.Z) / 2}
+}
+
+func (m *meshDomain) normal(v Vector) Vector {
+	if n, ok := m.normals[v]; ok {
+		return n
+	}
+	return m.samples[v]
+}
+
+func (m *meshDomain) direction(v Vector) Vector {
+	if v == (Vector{}) {
+		return Vector{0, 0, 1}
+	}
+	return v.Normalize()
+}
+
+func (m *meshDomain) point(v Vector, elevation float64) Vector {
+	n := m.normal(v)
+	if l := n.Length(); l > 0 {
+		n = n.MulScalar(1 / l)
+	}
+	return v.Add(n.MulScalar(elevation))
+}
+
+func (m *meshDomain) footprint(detail int) float64 {
+	return math.Ldexp(m.size, -detail)
+}
@@ -0,0 +1,149 @@
+package demsphere
+
+// Refinement selects how a Triangulator subdivides triangles.
+type Refinement int
+
+const (
+	// QuadSubdivision splits each triangle, or each quad of a cube-sphere,
+	// into four and then repairs the T-junctions between levels, which
+	// leaves slivers along the seams.
+	QuadSubdivision Refinement = iota
+
+	// EdgeBisection splits triangles in two across their refinement edge
+	// (newest vertex bisection), first refining the neighbor across that
+	// edge as needed so that the mesh is always conforming. Angles stay
+	// bounded, but the triangle count for a tolerance is not reliably
+	// lower: it is often higher from an icosahedron and lower from an
+	// octahedron or cube-sphere. Two bisections make up one detail level.
+	EdgeBisection
+)
+
+// bisectionNode is a triangle a, b, c of a bisection hierarchy, with b, c
+// its refinement edge.
+type bisectionNode struct {
+	a, b, c Vector
+	level   int
+	class   regionClass
+	split   bool
+}
+
+type bisectionEdge struct {
+	a, b Vector
+}
+
+// bisector refines a mesh by newest vertex bisection. edges maps each
+// directed edge of a leaf to that leaf.
+type bisector struct {
+	tri   *Triangulator
+	edges map[bisectionEdge]*bisectionNode
+	nodes []*bisectionNode
+	stack []*bisectionNode
+}
+
+func (tri *Triangulator) triangulateBisection(class regionClass) {
+	b := &bisector{tri: tri, edges: make(map[bisectionEdge]*bisectionNode)}
+	for _, t := range tri.domain.seed() {
+		b.add(b.marked(t, class))
+	}
+	for len(b.stack) > 0 {
+		n := b.stack[len(b.stack)-1]
+		b.stack = b.stack[:len(b.stack)-1]
+		if !n.split && b.needsRefinement(n) {
+			b.refine(n)
+		}
+	}
+	for _, n := range b.nodes {
+		if !n.split && n.class != regionOutside {
+			tri.leaf(n.level/2, n.class == regionBoundary, n.a, n.b, n.c)
+			tri.counts[n.level/2]++
+		}
+	}
+}
+
+// marked returns a seed triangle rotated so that its longest edge is the
+// refinement edge, breaking ties consistently so that neighbors agree.
+func (b *bisector) marked(t Triangle, class regionClass) *bisectionNode {
+	v := [3]Vector{t.A, t.B, t.C}
+	best := 0
+	for i := 1; i < 3; i++ {
+		if b.edgeLess(v[(best+1)%3], v[(best+2)%3], v[(i+1)%3], v[(i+2)%3]) {
+			best = i
+		}
+	}
+	return &bisectionNode{a: v[best], b: v[(best+1)%3], c: v[(best+2)%3], class: class}
+}
+
+// edgeLess orders undirected edges by length and then by their endpoints.
+func (b *bisector) edgeLess(a1, a2, b1, b2 Vector) bool {
+	d := b.tri.domain
+	la := d.point(a1, 0).Sub(d.point(a2, 0)).Length()
+	lb := d.point(b1, 0).Sub(d.point(b2, 0)).Length()
+	if la != lb {
+		return la < lb
+	}
+	if vectorLess(a2, a1) {
+		a1, a2 = a2, a1
+	}
+	if vectorLess(b2, b1) {
+		b1, b2 = b2, b1
+	}
+	if a1 != b1 {
+		return vectorLess(a1, b1)
+	}
+	return vectorLess(a2, b2)
+}
+
+func (b *bisector) add(n *bisectionNode) {
+	b.edges[bisectionEdge{n.a, n.b}] = n
+	b.edges[bisectionEdge{n.b, n.c}] = n
+	b.edges[bisectionEdge{n.c, n.a}] = n
+	b.nodes = append(b.nodes, n)
+	b.stack = append(b.stack, n)
+}
+
+func (b *bisector) needsRefinement(n *bisectionNode) bool {
+	tri := b.tri
+	if n.class == regionBoundary {
+		n.class = tri.classify(n.a, n.b, n.c)
+	}
+	if n.class == regionOutside {
+		return false
+	}
+	maxDetail := tri.maxDetailFor(n.a, n.b, n.c)
+	if n.level >= 2*maxDetail {
+		return false
+	}
+	detail := n.level / 2
+	if n.class == regionBoundary || detail < tri.minDetail {
+		return true
+	}
+	return !tri.accurate(detail, maxDetail, n.a, n.b, n.c)
+}
+
+// refine bisects a leaf together with its neighbor across the refinement
+// edge, first refining the neighbor if that edge is not its own refinement
+// edge.
+func (b *bisector) refine(n *bisectionNode) {
+	m := b.edges[bisectionEdge{n.c, n.b}]
+	if m != nil && (m.b != n.c || m.c != n.b) {
+		b.refine(m)
+		if n.split {
+			return
+		}
+		m = b.edges[bisectionEdge{n.c, n.b}]
+	}
+	v := b.tri.domain.midpoint(n.b, n.c)
+	b.bisect(n, v)
+	if m != nil {
+		b.bisect(m, v)
+	}
+}
+
+func (b *bisector) bisect(n *bisectionNode, v Vector) {
+	n.split = true
+	delete(b.edges, bisectionEdge{n.a, n.b})
+	delete(b.edges, bisectionEdge{n.b, n.c})
+	delete(b.edges, bisectionEdge{n.c, n.a})
+	b.add(&bisectionNode{a: v, b: n.a, c: n.b, level: n.level + 1, class: n.class})
+	b.add(&bisectionNode{a: v, b: n.c, c: n.a, level: n.level + 1, class: n.class})
+}
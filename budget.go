@@ -0,0 +1,156 @@
+package demsphere
+
+import (
+	"maps"
+	"math"
+)
+
+const maxBudgetIterations = 24
+
+// maxBudgetDrift is how many levels the tolerance may move, as a power of 4,
+// without the mesh changing before TriangulateBudget gives up.
+const maxBudgetDrift = 32
+
+// Tolerance returns the Triangulator's tolerance.
+func (tri *Triangulator) Tolerance() float64 {
+	return tri.tolerance
+}
+
+// Counts returns the number of triangles accepted at each detail level by the
+// last triangulation, before T-junctions were repaired.
+func (tri *Triangulator) Counts() map[int]int {
+	return maps.Clone(tri.counts)
+}
+
+// TriangulateBudget searches for the tolerance whose mesh has within margin
+// (a fraction, such as 0.05) of target triangles, starting from the current
+// tolerance, and returns that mesh. Until the target is bracketed, each
+// trial's tolerance is predicted from the per-level counts of the last. If no
+// tolerance lands within the margin, such as when the detail limits cannot
+// produce that many or that few triangles, it returns the largest mesh found
+// under the target, or else the smallest found. Tolerance reports the chosen
+// tolerance afterwards.
+func (tri *Triangulator) TriangulateBudget(target int, margin float64) []Triangle {
+	type trial struct {
+		tolerance float64
+		triangles []Triangle
+	}
+	var under, over *trial
+	var counts map[int]int
+	var step, drift float64
+	tolerance := tri.tolerance
+	for i := 0; i < maxBudgetIterations; i++ {
+		tri.tolerance = tolerance
+		triangles := tri.Triangulate()
+		n := len(triangles)
+		if math.Abs(float64(n-target)) <= margin*float64(target) {
+			return triangles
+		}
+		unchanged := maps.Equal(counts, tri.counts)
+		if !unchanged {
+			drift = 0
+		}
+		counts = tri.counts
+		t := &trial{tolerance, triangles}
+		if n > target {
+			if over == nil || tolerance > over.tolerance {
+				over = t
+			}
+		} else if under == nil || tolerance < under.tolerance {
+			under = t
+		}
+		switch {
+		case under != nil && over != nil:
+			x0, x1 := math.Log(over.tolerance), math.Log(under.tolerance)
+			y0, y1 := math.Log(float64(len(over.triangles))), math.Log(float64(len(under.triangles)))
+			if len(over.triangles) == len(under.triangles) || x1-x0 < 1e-6 {
+				break
+			}
+			// triangle count falls roughly as a power of the tolerance
+			f := (math.Log(float64(target)) - y0) / (y1 - y0)
+			f = clamp(f, 0.1, 0.9)
+			tolerance = math.Exp(x0 + f*(x1-x0))
+			continue
+		case unchanged && (tri.pinned(n > target) || math.Abs(drift) > maxBudgetDrift):
+			// the detail limits stop the mesh changing with the tolerance
+		default:
+			// no bound on the other side of the target yet, so predict one
+			// from the per-level counts, stepping at least a quarter level.
+			// Leaves held at a detail limit may be far from the tolerance,
+			// so the step doubles for as long as the mesh does not change.
+			var leaves int
+			for _, c := range counts {
+				leaves += c
+			}
+			k := tri.levelShift(float64(target) * float64(leaves) / float64(n))
+			if under == nil {
+				k = math.Min(k, -0.25)
+			} else {
+				k = math.Max(k, 0.25)
+			}
+			if unchanged && math.Abs(2*step) > math.Abs(k) {
+				k = clamp(2*step, -8, 8)
+			}
+			step = k
+			drift += k
+			tolerance *= math.Pow(4, -k)
+			continue
+		}
+		break
+	}
+	best := under
+	if best == nil {
+		best = over
+	}
+	if best.tolerance == tri.tolerance {
+		return best.triangles
+	}
+	// rerun the best trial so that the vertices and counts that Accuracy and
+	// Counts read belong to its mesh
+	tri.tolerance = best.tolerance
+	return tri.Triangulate()
+}
+
+// pinned reports whether every leaf of the last triangulation is at
+// minDetail, if coarse, or else at maxDetail or deeper, so that no
+// tolerance could move it further that way.
+func (tri *Triangulator) pinned(coarse bool) bool {
+	for d := range tri.counts {
+		if coarse && d > tri.minDetail || !coarse && d < tri.maxDetail {
+			return false
+		}
+	}
+	return true
+}
+
+// levelShift returns how many levels, possibly fractional, the leaves of the
+// last triangulation would have to move for there to be goal of them. Each
+// level deeper quarters a leaf's area, and leaves cannot rise above
+// minDetail or sink below their maximum detail level. A leaf's error falls
+// roughly fourfold with each level, so scaling the tolerance by 4^-k moves
+// the leaves by about k levels.
+func (tri *Triangulator) levelShift(goal float64) float64 {
+	// beyond these shifts every leaf is at its limit
+	lo, hi := 0.0, 0.0
+	for d := range tri.counts {
+		lo = math.Min(lo, float64(tri.minDetail-d))
+		hi = math.Max(hi, float64(tri.maxDetail-d))
+	}
+	leaves := func(k float64) float64 {
+		var n float64
+		for d, c := range tri.counts {
+			lo, hi := float64(min(d, tri.minDetail)), float64(max(d, tri.maxDetail))
+			n += float64(c) * math.Pow(4, clamp(float64(d)+k, lo, hi)-float64(d))
+		}
+		return n
+	}
+	for i := 0; i < 50; i++ {
+		k := (lo + hi) / 2
+		if leaves(k) < goal {
+			lo = k
+		} else {
+			hi = k
+		}
+	}
+	return (lo + hi) / 2
+}
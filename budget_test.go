@@ -0,0 +1,36 @@
+package demsphere
+
+import "testing"
+
+func TestTriangulateBudgetConverges(t *testing.T) {
+	for _, test := range []struct {
+		refinement Refinement
+		tolerance  float64
+		target     int
+	}{
+		{QuadSubdivision, 100, 5000},
+		{QuadSubdivision, 1, 20000},
+		{EdgeBisection, 100, 10000},
+	} {
+		tri := NewSourceTriangulator(testRegionSource(), 1, 6, 6371000, test.tolerance, 1, 1/6371000.0)
+		tri.Refinement = test.refinement
+		n := len(tri.TriangulateBudget(test.target, 0.05))
+		if d := float64(n-test.target) / float64(test.target); d < -0.05 || d > 0.05 {
+			t.Errorf("%+v: %d triangles at tolerance %g", test, n, tri.Tolerance())
+		}
+		if len(tri.triangles) != n {
+			t.Errorf("%+v: the Triangulator holds %d triangles of another trial", test, len(tri.triangles))
+		}
+	}
+}
+
+// A target beyond what the detail limits allow yields the nearest mesh.
+func TestTriangulateBudgetSaturates(t *testing.T) {
+	tri := NewSourceTriangulator(testRegionSource(), 1, 3, 6371000, 100, 1, 1/6371000.0)
+	if n := len(tri.TriangulateBudget(1000000, 0.05)); n != 20*4*4*4 {
+		t.Errorf("got %d triangles, want every triangle at the maximum detail", n)
+	}
+	if n := len(tri.TriangulateBudget(10, 0.05)); n != 20*4 {
+		t.Errorf("got %d triangles, want every triangle at the minimum detail", n)
+	}
+}
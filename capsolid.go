@@ -0,0 +1,192 @@
+package demsphere
+
+import (
+	"errors"
+	"math"
+)
+
+// CapSolid closes a regional surface mesh, such as the output of a
+// Triangulator with a Region, into a watertight solid: the terrain on top,
+// side walls running down from each boundary loop and a closed base.
+type CapSolid struct {
+	// BaseDepth is the distance from the lowest point of the surface down to
+	// the base, in output units.
+	BaseDepth float64
+
+	// Curved keeps the planet's curvature: the base is part of a sphere
+	// concentric with the surface and the walls point towards its center.
+	// Otherwise the base is a plane perpendicular to the region's mean
+	// normal and the walls are parallel to that normal.
+	Curved bool
+
+	// Up, if nonzero, is the direction of the walls of a flat base, in place
+	// of the region's mean normal.
+	Up Vector
+}
+
+func NewCapSolid(baseDepth float64, curved bool) *CapSolid {
+	return &CapSolid{BaseDepth: baseDepth, Curved: curved}
+}
+
+// Build returns the surface triangles followed by the walls and the base.
+func (c *CapSolid) Build(surface []Triangle) ([]Triangle, error) {
+	loops, err := boundaryLoops(surface)
+	if err != nil {
+		return nil, err
+	}
+	if len(loops) == 0 {
+		return nil, errors.New("surface has no boundary")
+	}
+
+	// corners lists the indices of the loop vertices that are corners of the
+	// base. The walls below the vertices between two corners share one base
+	// edge.
+	corners := make([][]int, len(loops))
+	var base func(Vector) Vector
+	var bottom []Triangle
+	if c.Curved {
+		radius := math.Inf(1)
+		for _, t := range surface {
+			radius = math.Min(radius, math.Min(t.A.Length(), math.Min(t.B.Length(), t.C.Length())))
+		}
+		radius -= c.BaseDepth
+		if radius <= 0 {
+			return nil, errors.New("base depth reaches the center of the planet")
+		}
+		base = func(p Vector) Vector {
+			return p.Normalize().MulScalar(radius)
+		}
+		for _, t := range surface {
+			bottom = append(bottom, Triangle{base(t.C), base(t.B), base(t.A)})
+		}
+		for i, loop := range loops {
+			for j := range loop {
+				corners[i] = append(corners[i], j)
+			}
+		}
+	} else {
+		up := c.Up
+		if up == (Vector{}) {
+			for _, t := range surface {
+				up = up.Add(t.B.Sub(t.A).Cross(t.C.Sub(t.A)))
+			}
+		}
+		up = up.Normalize()
+		h := math.Inf(1)
+		for _, t := range surface {
+			h = math.Min(h, math.Min(t.A.Dot(up), math.Min(t.B.Dot(up), t.C.Dot(up))))
+		}
+		h -= c.BaseDepth
+		base = func(p Vector) Vector {
+			return p.Sub(up.MulScalar(p.Dot(up) - h))
+		}
+		e1 := Vector{0, 0, 1}.Cross(up)
+		if e1.Length() < 1e-9 {
+			e1 = Vector{0, 1, 0}
+		}
+		e1 = e1.Normalize()
+		e2 := up.Cross(e1)
+		for i, loop := range loops {
+			polygon := make([]regionVertex, len(loop))
+			for j, p := range loop {
+				polygon[j] = regionVertex{point{p.Dot(e1), p.Dot(e2)}, base(p)}
+			}
+			corners[i] = polygonCorners(polygon)
+			kept := make([]regionVertex, len(corners[i]))
+			for k, j := range corners[i] {
+				kept[k] = polygon[j]
+			}
+			for _, t := range earClip(kept, true) {
+				bottom = append(bottom, Triangle{t[2], t[1], t[0]})
+			}
+		}
+	}
+
+	result := append([]Triangle(nil), surface...)
+	for i, loop := range loops {
+		for k, first := range corners[i] {
+			last := corners[i][(k+1)%len(corners[i])]
+			for j := first; j != last; j = (j + 1) % len(loop) {
+				a, b := loop[j], loop[(j+1)%len(loop)]
+				result = append(result, Triangle{b, a, base(loop[first])})
+			}
+			result = append(result, Triangle{loop[last], base(loop[first]), base(loop[last])})
+		}
+	}
+	return append(result, bottom...), nil
+}
+
+// polygonCorners returns the indices of the vertices of a polygon that do
+// not lie on the straight line from the previous corner to the next vertex,
+// within the tolerance that Validate uses for degenerate triangles.
+func polygonCorners(polygon []regionVertex) []int {
+	// the lowest vertex, leftmost among ties, is always a corner
+	start := 0
+	for i, v := range polygon {
+		if v.p.Y < polygon[start].p.Y || v.p.Y == polygon[start].p.Y && v.p.X < polygon[start].p.X {
+			start = i
+		}
+	}
+	n := len(polygon)
+	corners := []int{start}
+	for k := 1; k < n; k++ {
+		i := (start + k) % n
+		a, b, c := polygon[corners[len(corners)-1]].p, polygon[i].p, polygon[(i+1)%n].p
+		ab, bc, ac := b.sub(a), c.sub(b), c.sub(a)
+		longest := math.Max(math.Hypot(ab.X, ab.Y), math.Max(math.Hypot(bc.X, bc.Y), math.Hypot(ac.X, ac.Y)))
+		if math.Abs(ab.cross(ac)) <= 1e-12*longest*longest && ab.X*bc.X+ab.Y*bc.Y >= 0 {
+			continue
+		}
+		corners = append(corners, i)
+	}
+	return corners
+}
+
+// boundaryLoops returns the closed loops formed by the edges of a mesh that
+// belong to a single triangle, each running counter-clockwise around the
+// mesh as seen from outside.
+func boundaryLoops(triangles []Triangle) ([][]Vector, error) {
+	type edge struct{ a, b Vector }
+	edges := make(map[edge]bool)
+	for _, t := range triangles {
+		edges[edge{t.A, t.B}] = true
+		edges[edge{t.B, t.C}] = true
+		edges[edge{t.C, t.A}] = true
+	}
+	next := make(map[Vector]Vector)
+	var order []Vector
+	for _, t := range triangles {
+		for _, e := range [3]edge{{t.A, t.B}, {t.B, t.C}, {t.C, t.A}} {
+			if edges[edge{e.b, e.a}] {
+				continue
+			}
+			if _, ok := next[e.a]; ok {
+				return nil, errors.New("surface boundary is not a set of simple loops")
+			}
+			next[e.a] = e.b
+			order = append(order, e.a)
+		}
+	}
+	var loops [][]Vector
+	visited := make(map[Vector]bool)
+	for _, start := range order {
+		if visited[start] {
+			continue
+		}
+		var loop []Vector
+		for v := start; ; {
+			visited[v] = true
+			loop = append(loop, v)
+			n, ok := next[v]
+			if !ok || visited[n] && n != start {
+				return nil, errors.New("surface boundary is not closed")
+			}
+			if n == start {
+				break
+			}
+			v = n
+		}
+		loops = append(loops, loop)
+	}
+	return loops, nil
+}
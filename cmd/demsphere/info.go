@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"os"
+
+	demsphere "dem"
+)
+
+// infoReport is the report printed by the info command.
+type infoReport struct {
+	Path     string `json:"path"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	DataType string `json:"data_type"`
+
+	Projection        string      `json:"projection"`
+	Bounds            *[4]float64 `json:"bounds,omitempty"` // left, right, top, bottom
+	PrimeMeridian     float64     `json:"prime_meridian"`
+	WestPositive      bool        `json:"west_positive"`
+	Extent            *[4]float64 `json:"extent,omitempty"` // min x, max x, min y, max y
+	CentralMeridian   float64     `json:"central_meridian"`
+	TrueScaleLatitude float64     `json:"true_scale_latitude"`
+
+	Radius            float64 `json:"radius"`
+	ResolutionDegrees float64 `json:"resolution_degrees"`
+	MetersPerPixel    float64 `json:"meters_per_pixel"`
+
+	ElevationRange [2]float64 `json:"elevation_range"`
+	MinElevation   float64    `json:"min_elevation"`
+	MaxElevation   float64    `json:"max_elevation"`
+	MeanElevation  float64    `json:"mean_elevation"`
+	Histogram      []infoBin  `json:"histogram"`
+	NoDataPixels   int        `json:"nodata_pixels"`
+	NoDataFraction float64    `json:"nodata_fraction"`
+}
+
+type infoBin struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+func dataType(im image.Image) string {
+	switch im.(type) {
+	case *image.Gray16:
+		return "uint16 grayscale"
+	case *image.Gray:
+		return "uint8 grayscale"
+	case *image.RGBA64, *image.NRGBA64:
+		return "uint16 color"
+	case *image.RGBA, *image.NRGBA, *image.Paletted, *image.YCbCr:
+		return "uint8 color"
+	}
+	return fmt.Sprintf("%T", im)
+}
+
+func info() {
+	georef, err := inputProjection()
+	if err != nil {
+		log.Fatal(err)
+	}
+	texture, im, err := readTexture(*infoFile, georef)
+	if err != nil {
+		log.Fatal(err)
+	}
+	texture.MaskNoData(*nodata...)
+	stats := texture.Stats(*infoBins)
+
+	radius := *infoRadius
+	if radius <= 0 {
+		radius = float64(MeanRadius)
+	}
+	lo, hi := float64(MinElevation), float64(MaxElevation)
+	elevation := func(v float64) float64 {
+		return lo + v*(hi-lo)
+	}
+
+	r := infoReport{
+		Path:              *infoFile,
+		Width:             texture.W,
+		Height:            texture.H,
+		DataType:          dataType(im),
+		Projection:        *projection,
+		Radius:            radius,
+		ResolutionDegrees: texture.Resolution() * 180 / math.Pi,
+		ElevationRange:    [2]float64{lo, hi},
+		MinElevation:      elevation(stats.Min),
+		MaxElevation:      elevation(stats.Max),
+		MeanElevation:     elevation(stats.Mean),
+		NoDataPixels:      stats.NoData,
+		NoDataFraction:    float64(stats.NoData) / float64(len(texture.Pix)),
+	}
+	switch p := georef.(type) {
+	case demsphere.Equirectangular:
+		r.Bounds = &[4]float64{p.Left, p.Right, p.Top, p.Bottom}
+		r.PrimeMeridian = p.PrimeMeridian
+		r.WestPositive = p.WestPositive
+		// at the equator
+		r.MetersPerPixel = math.Abs(p.Right-p.Left) / float64(texture.W) * math.Pi / 180 * radius
+	case demsphere.PolarStereographic:
+		r.Extent = &[4]float64{p.MinX, p.MaxX, p.MinY, p.MaxY}
+		r.CentralMeridian = p.CentralMeridian
+		r.TrueScaleLatitude = p.TrueScaleLatitude
+		// at the latitude of true scale
+		r.MetersPerPixel = (p.MaxX - p.MinX) / float64(texture.W)
+	}
+	for i, n := range stats.Histogram {
+		a := float64(i) / float64(len(stats.Histogram))
+		b := float64(i+1) / float64(len(stats.Histogram))
+		r.Histogram = append(r.Histogram, infoBin{elevation(a), elevation(b), n})
+	}
+
+	fmt.Printf("\nFile: %s\n", r.Path)
+	fmt.Printf("Dimensions: %d x %d\n", r.Width, r.Height)
+	fmt.Printf("Data type: %s\n", r.DataType)
+	if r.Bounds != nil {
+		fmt.Printf("Projection: %s, bounds %g,%g,%g,%g, prime meridian %g, west positive %v\n",
+			r.Projection, r.Bounds[0], r.Bounds[1], r.Bounds[2], r.Bounds[3], r.PrimeMeridian, r.WestPositive)
+		fmt.Printf("Resolution: %.6g degrees, %.1f m per pixel at the equator (radius %g m)\n",
+			r.ResolutionDegrees, r.MetersPerPixel, r.Radius)
+	} else {
+		fmt.Printf("Projection: %s, extent %g,%g,%g,%g, central meridian %g, true scale latitude %g\n",
+			r.Projection, r.Extent[0], r.Extent[1], r.Extent[2], r.Extent[3], r.CentralMeridian, r.TrueScaleLatitude)
+		fmt.Printf("Resolution: %.6g degrees, %.1f m per pixel at the latitude of true scale\n",
+			r.ResolutionDegrees, r.MetersPerPixel)
+	}
+	fmt.Printf("Elevation: min %.1f m, max %.1f m, mean %.1f m (range %g..%g m)\n",
+		r.MinElevation, r.MaxElevation, r.MeanElevation, lo, hi)
+	fmt.Printf("NoData: %d pixels (%.3f%%)\n", r.NoDataPixels, 100*r.NoDataFraction)
+	fmt.Println("Histogram:")
+	for _, b := range r.Histogram {
+		fmt.Printf("  %9.1f .. %9.1f m: %d\n", b.Min, b.Max, b.Count)
+	}
+
+	if *infoJSON == "" {
+		return
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	data = append(data, '\n')
+	if err := os.WriteFile(*infoJSON, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
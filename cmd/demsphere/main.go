@@ -2,141 +2,210 @@ package main
 
 import (
 	"fmt"
+	"image"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/disintegration/imaging"
-	"github.com/fogleman/demsphere"
-	"github.com/fogleman/fauxgl"
 	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/fogleman/fauxgl"
+
+	demsphere "dem"
 )
 
 var (
-	inputFile  = kingpin.Flag("input", "Input DEM image to process.").Required().Short('i').ExistingFile()
+	inputFile = kingpin.Flag("input", "Input DEM image to process (required unless --harmonics is given).").Short('i').ExistingFile()
 	//outputFile = kingpin.Flag("output", "Output STL file to write.").Required().Short('o').String()
-	Planet = "Earth"
-	MinDetail = 9
-	MaxDetail = 12
-	MeanRadius = 6373934
-	MinElevation = -10900
-	MaxElevation = 8849
-	Tolerance = 50
-	Exaggeration = 15
+	filter          = kingpin.Flag("filter", "DEM sampling filter: bilinear (fastest), bicubic (1.3-1.6x the meshing time) or lanczos (2.7-3.9x).").Default("bilinear").Enum("bilinear", "bicubic", "lanczos")
+	nodata          = kingpin.Flag("nodata", "Raw DEM value marking missing data, e.g. -32768 (repeatable).").Float64List()
+	fill            = kingpin.Flag("fill", "NoData hole filling method: nearest, idw or diffusion.").Default("diffusion").Enum("nearest", "idw", "diffusion")
+	projection      = kingpin.Flag("projection", "Input DEM projection: equirectangular, north-polar or south-polar (stereographic).").Default("equirectangular").Enum("equirectangular", "north-polar", "south-polar")
+	bounds          = kingpin.Flag("bounds", "Equirectangular raster edges in degrees as LEFT,RIGHT,TOP,BOTTOM, in the dataset's longitude convention.").Default("-180,180,90,-90").String()
+	primeMeridian   = kingpin.Flag("prime-meridian", "East longitude of the dataset's zero meridian in degrees.").Default("0").Float64()
+	westPositive    = kingpin.Flag("west-positive", "Dataset longitudes increase to the west.").Bool()
+	polarExtent     = kingpin.Flag("polar-extent", "Polar stereographic raster extent in meters as MINX,MAXX,MINY,MAXY.").String()
+	centralMeridian = kingpin.Flag("central-meridian", "Polar stereographic central meridian in degrees east.").Default("0").Float64()
+	trueScale       = kingpin.Flag("true-scale-latitude", "Polar stereographic latitude of true scale in degrees (0 for the pole).").Default("0").Float64()
+	northCap        = kingpin.Flag("north-cap", "North polar stereographic DEM to merge over the global DEM.").ExistingFile()
+	northCapExtent  = kingpin.Flag("north-cap-extent", "North cap raster extent in meters as MINX,MAXX,MINY,MAXY.").String()
+	southCap        = kingpin.Flag("south-cap", "South polar stereographic DEM to merge over the global DEM.").ExistingFile()
+	southCapExtent  = kingpin.Flag("south-cap-extent", "South cap raster extent in meters as MINX,MAXX,MINY,MAXY.").String()
+	capElevation    = kingpin.Flag("cap-elevation", "Elevation range of the polar cap DEMs in meters as MIN,MAX (defaults to the global range).").String()
+	capLatitude     = kingpin.Flag("cap-latitude", "Absolute latitude poleward of which the polar caps are used.").Default("80").Float64()
+	capFeather      = kingpin.Flag("cap-feather", "Width in degrees of the band over which polar caps blend into the global DEM.").Default("2").Float64()
+	capEdgeFeather  = kingpin.Flag("cap-edge-feather", "Width in cap pixels of the band inside a polar cap's edge over which it blends into the global DEM.").Default("16").Float64()
+	inserts         = kingpin.Flag("insert", "Regional DEM layered over the global DEM as PATH@LEFT,RIGHT,TOP,BOTTOM@MINELEV,MAXELEV@MAXDETAIL (repeatable, later inserts on top).").Strings()
+	insertFeather   = kingpin.Flag("insert-feather", "Width in insert pixels of the band over which inserts blend into the layers beneath.").Default("16").Float64()
+	regionBounds    = kingpin.Flag("region-bounds", "Only mesh the region between SOUTH,NORTH,WEST,EAST in degrees (west greater than east crosses the antimeridian).").String()
+	regionGeoJSON   = kingpin.Flag("region-geojson", "Only mesh the region inside the polygons of a GeoJSON file (without holes or overlaps).").ExistingFile()
+	relief          = kingpin.Flag("relief", "Build a flat raised-relief tile instead of a globe, in the given map projection: equirectangular, mercator, north-polar or south-polar. Cylindrical tiles cover --region-bounds.").Enum("equirectangular", "mercator", "north-polar", "south-polar")
+	reliefExtent    = kingpin.Flag("relief-extent", "Polar relief tile extent in meters from the pole as MINX,MAXX,MINY,MAXY.").String()
+	ellipsoid       = kingpin.Flag("ellipsoid", "Reference ellipsoid semi-axes in meters as EQUATORIAL,POLAR (oblate spheroid) or A,B,C (triaxial), instead of a sphere of the mean radius.").String()
+	geodetic        = kingpin.Flag("geodetic", "DEM latitudes are geodetic (planetographic) rather than geocentric; requires --ellipsoid.").Bool()
+	radiusRange     = kingpin.Flag("radius-model", "The DEM is a shape model holding the radius from the body's center rather than elevation, ranging over MIN,MAX in --radius-units.").String()
+	radiusUnits     = kingpin.Flag("radius-units", "Units of --radius-model: m or km.").Default("km").Enum("m", "km")
+	baseMesh        = kingpin.Flag("base-mesh", "Closed OBJ, STL or PLY shape model to subdivide and displace along its normals by the DEM, instead of a sphere.").ExistingFile()
+	baseMeshUnits   = kingpin.Flag("base-mesh-units", "Units of --base-mesh: m or km.").Default("km").Enum("m", "km")
+	wallThickness   = kingpin.Flag("wall-thickness", "Hollow a --base-mesh model with an inner shell this many meters below its lowest surface, offset along the base mesh's normals; 0 leaves it solid.").Default("0").Float64()
+	harmonics       = kingpin.Flag("harmonics", "Spherical harmonic topography or shape coefficient table (degree, order, C, S per line) to use instead of a DEM image.").ExistingFile()
+	harmonicsDegree = kingpin.Flag("harmonics-degree", "Truncate the spherical harmonic expansion at this degree (0 for all).").Default("0").Int()
+	harmonicsUnits  = kingpin.Flag("harmonics-units", "Units of the spherical harmonic coefficients: m or km.").Default("m").Enum("m", "km")
+	harmonicsOffset = kingpin.Flag("harmonics-reference", "Reference radius in meters subtracted from the expansion, for shape models whose C00 term is the mean radius.").Default("0").Float64()
+	seed            = kingpin.Flag("seed", "Polyhedron subdivided to mesh the sphere: icosahedron (most uniform), octahedron (edges on the equator and prime meridian) or cube (a cube-sphere refined as quads on each face of a cube map).").Default("icosahedron").Enum("icosahedron", "octahedron", "cube")
+	refinement      = kingpin.Flag("refinement", "Adaptive refinement: quad (1-to-4 subdivision) or bisection (longest-edge bisection, conforming with bounded angles).").Default("quad").Enum("quad", "bisection")
+	metric          = kingpin.Flag("metric", "Error metric for adaptive refinement: distance (max distance from the plane, fastest), vertical (max vertical error), rms, normal (normal deviation in degrees) or print (millimeters on the print).").Default("distance").Enum("distance", "vertical", "rms", "normal", "print")
+	metricTolerance = kingpin.Flag("metric-tolerance", "Tolerance in the units of --metric (meters, degrees or millimeters), required for normal and print; 0 uses the mesh tolerance for the others.").Default("0").Float64()
+	mmPerUnit       = kingpin.Flag("mm-per-unit", "Millimeters per output unit, for --metric print.").Default("1").Float64()
+	toleranceSpace  = kingpin.Flag("tolerance-space", "Space in which the tolerance is measured: source (meters on the planet), exaggerated (meters after exaggeration) or output (final mesh units).").Default("source").Enum("source", "exaggerated", "output")
+	toleranceMap    = kingpin.Flag("tolerance-map", "Global equirectangular raster of tolerances as PATH@MIN,MAX, mapping its darkest to brightest pixels onto MIN..MAX in the units of the tolerance.").String()
+	toleranceRegion = kingpin.Flag("tolerance-region", "Region with its own tolerance and maximum detail (0 to keep the global one) as SOUTH,NORTH,WEST,EAST@TOLERANCE@MAXDETAIL or GEOJSON@TOLERANCE@MAXDETAIL (repeatable).").Strings()
+	targetTriangles = kingpin.Flag("target-triangles", "Search for the tolerance giving about this many triangles in total, e.g. a slicer's limit.").Int()
+	targetSize      = kingpin.Flag("target-size", "Search for the tolerance giving a binary STL of about this many megabytes.").Float64()
+	targetMargin    = kingpin.Flag("target-margin", "Percentage within which --target-triangles or --target-size must be met.").Default("5").Float64()
+	solid           = kingpin.Flag("solid", "Close a regional mesh into a watertight solid with side walls and a base, instead of a hollow shell.").Bool()
+	baseDepth       = kingpin.Flag("base-depth", "Depth in meters of the solid's base below the lowest point of the region.").Default("5000").Float64()
+	flatBase        = kingpin.Flag("flat-base", "Give the solid a flat base instead of one following the planet's curvature.").Bool()
+	accuracy        = kingpin.Flag("accuracy", "Report the outer mesh's vertical error against the DEM after meshing.").Bool()
+	accuracyDepth   = kingpin.Flag("accuracy-depth", "Maximum subdivisions of each triangle when sampling its error.").Default("4").Int()
+	heatmap         = kingpin.Flag("heatmap", "Write an equirectangular PNG of the outer mesh's largest vertical error per pixel (implies --accuracy).").String()
+	heatmapWidth    = kingpin.Flag("heatmap-width", "Width in pixels of the --heatmap.").Default("1440").Int()
+	heatmapLimit    = kingpin.Flag("heatmap-limit", "Error in meters shown brightest in the --heatmap (0 for the largest error).").Default("0").Float64()
+	validateMesh    = kingpin.Flag("validate", "Check the mesh for holes, non-manifold edges, inconsistent winding, degenerate and duplicate triangles and intersecting shells before writing it.").Bool()
+	autoDetail      = kingpin.Flag("auto-detail", "Set MaxDetail to the level recommended from the DEM's resolution, beyond which triangles are smaller than its pixels.").Bool()
+	mipmap          = kingpin.Flag("mipmap", "Sample the DEM from a mipmap pyramid matched to each triangle's footprint.").Bool()
+	meshCommand     = kingpin.Command("mesh", "Generate an STL mesh (the default).").Default()
+	estimateCommand = kingpin.Command("estimate", "Project a mesh's triangle and vertex counts, peak memory, STL size and run time from a sampled pass, without building it.")
+	estimateSample  = estimateCommand.Flag("sample", "Fraction of the surface refined by the sampled pass.").Default("0.02").Float64()
+	infoCommand     = kingpin.Command("info", "Report a DEM's dimensions, georeferencing, resolution and elevation statistics.")
+	infoFile        = infoCommand.Arg("dem", "DEM image to describe.").Required().ExistingFile()
+	infoJSON        = infoCommand.Flag("json", "Also write the report as JSON to this file.").String()
+	infoRadius      = infoCommand.Flag("radius", "Body radius in meters for the pixel size (defaults to the mean radius).").Float64()
+	validateCommand = kingpin.Command("validate", "Check a mesh for holes, non-manifold edges, inconsistent winding, degenerate and duplicate triangles and intersecting shells.")
+	validateFile    = validateCommand.Arg("mesh", "OBJ, STL or PLY mesh to check.").Required().ExistingFile()
+	validateJSON    = validateCommand.Flag("json", "Also write the report as JSON to this file.").String()
+	infoBins        = infoCommand.Flag("bins", "Number of elevation histogram bins.").Default("16").Int()
+	Planet          = "Earth"
+	MinDetail       = 9
+	MaxDetail       = 12
+	MeanRadius      = 6373934
+	MinElevation    = -10900
+	MaxElevation    = 8849
+	Tolerance       = 50
+	Exaggeration    = 15
 	//Scale = 1/MeanRadius
 	InnerShellScale = 0.5
 )
 
-	// Planet: "Mercury"
-    // MinDetail: 10
-    // MaxDetail: 15
-    // MeanRadius: 2439700
-    // MinElevation: -5500
-    // MaxElevation: 5500
-    // Tolerance: 50
-    // Exaggeration: 15
-	// Scale = 1/MeanRadius
-	// InnerShellScale = 0.9
-
-	// Planet: "Venus"
-    // MinDetail: 10
-    // MaxDetail: 15
-    // MeanRadius: 6051800
-    // MinElevation: -1000
-    // MaxElevation: 11000
-    // Tolerance: 50
-    // Exaggeration: 15
-	// Scale = 1/MeanRadius
-	// InnerShellScale = 0.9
-
-	// Planet: "Test"
-    // MinDetail: 100
-    // MaxDetail: 200
-    // MeanRadius: 1
-    // MinElevation: -1
-    // MaxElevation: 2
-    // Tolerance: 50
-    // Exaggeration: 20
-	// Scale = 1/MeanRadius
-	// InnerShellScale = 0.9
-
-	// Planet = "Earth"
-	// MinDetail = 9
-	// MaxDetail = 12
-	// MeanRadius = 6373934
-	// MinElevation = -10900
-	// MaxElevation = 8849
-	// Tolerance = 50
-	// Exaggeration = 15
-	// //Scale = 1/MeanRadius
-	// InnerShellScale = 0.9
-
-	// Planet: "Mars"
-    // MinDetail: 10
-    // MaxDetail: 15
-    // MeanRadius: 3389500
-    // MinElevation: -11000
-    // MaxElevation: 21900
-    // Tolerance: 50
-    // Exaggeration: 10
-	// Scale = 1/MeanRadius
-	// InnerShellScale = 0.9
-
-	// Planet: "Jupiter"
-    // MinDetail: 10
-    // MaxDetail: 15
-    // MeanRadius: 69911000
-    // MinElevation: -1000
-    // MaxElevation: 1000
-    // Tolerance: 50
-    // Exaggeration: 30
-	// Scale = 1/MeanRadius
-	// InnerShellScale = 0.9
-
-	// Planet: "Saturn"
-    // MinDetail: 10
-    // MaxDetail: 15
-    // MeanRadius: 58232000
-    // MinElevation: -1000
-    // MaxElevation: 1000
-    // Tolerance: 50
-    // Exaggeration: 30
-	// Scale = 1/MeanRadius
-	// InnerShellScale = 0.9
-
-	// Planet: "Uranus"
-    // MinDetail: 10
-    // MaxDetail: 15
-    // MeanRadius: 25362000
-    // MinElevation: -1000
-    // MaxElevation: 1000
-    // Tolerance: 50
-    // Exaggeration: 30
-	// Scale = 1/MeanRadius
-	// InnerShellScale = 0.9
-
-	// Planet: "Neptune"
-    // MinDetail: 10
-    // MaxDetail: 15
-    // MeanRadius: 24622000
-    // MinElevation: -1000
-    // MaxElevation: 1000
-    // Tolerance: 50
-    // Exaggeration: 30
-	// Scale = 1/MeanRadius
-	// InnerShellScale = 0.9
-
-	// Planet: "Moon"
-    // MinDetail: 10
-    // MaxDetail: 15
-    // MeanRadius: 1737100
-    // MinElevation: -9000
-    // MaxElevation: 10800
-    // Tolerance: 50
-    // Exaggeration: 15
-	// Scale = 1/MeanRadius
-	// InnerShellScale = 0.9
+// Planet: "Mercury"
+// MinDetail: 10
+// MaxDetail: 15
+// MeanRadius: 2439700
+// MinElevation: -5500
+// MaxElevation: 5500
+// Tolerance: 50
+// Exaggeration: 15
+// Scale = 1/MeanRadius
+// InnerShellScale = 0.9
+
+// Planet: "Venus"
+// MinDetail: 10
+// MaxDetail: 15
+// MeanRadius: 6051800
+// MinElevation: -1000
+// MaxElevation: 11000
+// Tolerance: 50
+// Exaggeration: 15
+// Scale = 1/MeanRadius
+// InnerShellScale = 0.9
+
+// Planet: "Test"
+// MinDetail: 100
+// MaxDetail: 200
+// MeanRadius: 1
+// MinElevation: -1
+// MaxElevation: 2
+// Tolerance: 50
+// Exaggeration: 20
+// Scale = 1/MeanRadius
+// InnerShellScale = 0.9
+
+// Planet = "Earth"
+// MinDetail = 9
+// MaxDetail = 12
+// MeanRadius = 6373934
+// MinElevation = -10900
+// MaxElevation = 8849
+// Tolerance = 50
+// Exaggeration = 15
+// //Scale = 1/MeanRadius
+// InnerShellScale = 0.9
 
+// Planet: "Mars"
+// MinDetail: 10
+// MaxDetail: 15
+// MeanRadius: 3389500
+// MinElevation: -11000
+// MaxElevation: 21900
+// Tolerance: 50
+// Exaggeration: 10
+// Scale = 1/MeanRadius
+// InnerShellScale = 0.9
 
+// Planet: "Jupiter"
+// MinDetail: 10
+// MaxDetail: 15
+// MeanRadius: 69911000
+// MinElevation: -1000
+// MaxElevation: 1000
+// Tolerance: 50
+// Exaggeration: 30
+// Scale = 1/MeanRadius
+// InnerShellScale = 0.9
+
+// Planet: "Saturn"
+// MinDetail: 10
+// MaxDetail: 15
+// MeanRadius: 58232000
+// MinElevation: -1000
+// MaxElevation: 1000
+// Tolerance: 50
+// Exaggeration: 30
+// Scale = 1/MeanRadius
+// InnerShellScale = 0.9
+
+// Planet: "Uranus"
+// MinDetail: 10
+// MaxDetail: 15
+// MeanRadius: 25362000
+// MinElevation: -1000
+// MaxElevation: 1000
+// Tolerance: 50
+// Exaggeration: 30
+// Scale = 1/MeanRadius
+// InnerShellScale = 0.9
+
+// Planet: "Neptune"
+// MinDetail: 10
+// MaxDetail: 15
+// MeanRadius: 24622000
+// MinElevation: -1000
+// MaxElevation: 1000
+// Tolerance: 50
+// Exaggeration: 30
+// Scale = 1/MeanRadius
+// InnerShellScale = 0.9
+
+// Planet: "Moon"
+// MinDetail: 10
+// MaxDetail: 15
+// MeanRadius: 1737100
+// MinElevation: -9000
+// MaxElevation: 10800
+// Tolerance: 50
+// Exaggeration: 15
+// Scale = 1/MeanRadius
+// InnerShellScale = 0.9
 
 func timed(name string) func() {
 	if len(name) > 0 {
@@ -148,59 +217,742 @@ func timed(name string) func() {
 	}
 }
 
-func main() {
-	var done func()
+func parseFloats(s string, n int) ([]float64, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != n {
+		return nil, fmt.Errorf("expected %d comma-separated values, got %q", n, s)
+	}
+	result := make([]float64, n)
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = v
+	}
+	return result, nil
+}
 
-	kingpin.Parse()
+func inputProjection() (demsphere.Projection, error) {
+	if *projection == "equirectangular" {
+		b, err := parseFloats(*bounds, 4)
+		if err != nil {
+			return nil, err
+		}
+		return demsphere.Equirectangular{
+			Left: b[0], Right: b[1], Top: b[2], Bottom: b[3],
+			PrimeMeridian: *primeMeridian,
+			WestPositive:  *westPositive,
+		}, nil
+	}
+	e, err := parseFloats(*polarExtent, 4)
+	if err != nil {
+		return nil, err
+	}
+	return demsphere.PolarStereographic{
+		South:             *projection == "south-polar",
+		Radius:            float64(MeanRadius),
+		CentralMeridian:   *centralMeridian,
+		TrueScaleLatitude: *trueScale,
+		MinX:              e[0], MaxX: e[1], MinY: e[2], MaxY: e[3],
+	}, nil
+}
 
-	done = timed("Reading input DEM")
-	im, err := fauxgl.LoadImage(*inputFile)
+// readTexture reads a DEM image as a texture without treating NoData.
+func readTexture(path string, projection demsphere.Projection) (*demsphere.Texture, image.Image, error) {
+	done := timed(fmt.Sprintf("Reading %s", path))
+	im, err := fauxgl.LoadImage(path)
 	done()
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
+	}
+	texture := demsphere.NewTexture(im)
+	texture.Projection = projection
+	return texture, im, nil
+}
+
+func loadTexture(path string, projection demsphere.Projection) (*demsphere.Texture, error) {
+	fills := map[string]demsphere.FillMethod{
+		"nearest":   demsphere.FillNearest,
+		"idw":       demsphere.FillInverseDistance,
+		"diffusion": demsphere.FillDiffusion,
+	}
+	filters := map[string]demsphere.Filter{
+		"bilinear": demsphere.Bilinear,
+		"bicubic":  demsphere.Bicubic,
+		"lanczos":  demsphere.Lanczos3,
 	}
 
-	// Print all the variables
-	output := fmt.Sprintf("\nPlanet: %s\nMinDetail: %d\nMaxDetail: %d\nMeanRadius: %d\nMinElevation: %d\nMaxElevation: %d\nTolerance: %d\nExaggeration: %d\nInnerShellScale: %f\n",
-        Planet,
-        MinDetail,
-        MaxDetail,
-        MeanRadius,
-        MinElevation,
-        MaxElevation,
-        Tolerance,
-        Exaggeration,
-        //Scale,
-        InnerShellScale,
-    )
+	texture, _, err := readTexture(path, projection)
+	if err != nil {
+		return nil, err
+	}
+	texture.Filter = filters[*filter]
+	if missing := texture.MaskNoData(*nodata...); missing > 0 {
+		done := timed("Filling NoData holes")
+		filled := texture.FillNoData(fills[*fill])
+		done()
+		if filled == 0 {
+			return nil, fmt.Errorf("%s has no valid pixels", path)
+		}
+		fmt.Printf("Filled %d NoData pixels (%.3f%%)\n", filled, 100*float64(filled)/float64(len(texture.Pix)))
+	}
+	return texture, nil
+}
 
-    fmt.Println(output)
+func loadCap(path, extent string, south bool, minElevation, maxElevation float64) (demsphere.ElevationSource, error) {
+	if path == "" {
+		return nil, nil
+	}
+	e, err := parseFloats(extent, 4)
+	if err != nil {
+		return nil, err
+	}
+	projection := demsphere.PolarStereographic{
+		South:             south,
+		Radius:            float64(MeanRadius),
+		CentralMeridian:   *centralMeridian,
+		TrueScaleLatitude: *trueScale,
+		MinX:              e[0], MaxX: e[1], MinY: e[2], MaxY: e[3],
+	}
+	texture, err := loadTexture(path, projection)
+	if err != nil {
+		return nil, err
+	}
+	return &demsphere.TextureSource{Texture: texture, MinElevation: minElevation, MaxElevation: maxElevation}, nil
+}
+
+func loadInsert(spec string, priority int) (*demsphere.Insert, error) {
+	parts := strings.Split(spec, "@")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid insert %q", spec)
+	}
+	b, err := parseFloats(parts[1], 4)
+	if err != nil {
+		return nil, err
+	}
+	r, err := parseFloats(parts[2], 2)
+	if err != nil {
+		return nil, err
+	}
+	maxDetail, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, err
+	}
+	projection := demsphere.Equirectangular{
+		Left: b[0], Right: b[1], Top: b[2], Bottom: b[3],
+		PrimeMeridian: *primeMeridian,
+		WestPositive:  *westPositive,
+	}
+	texture, err := loadTexture(parts[0], projection)
+	if err != nil {
+		return nil, err
+	}
+	return &demsphere.Insert{
+		Source:    &demsphere.TextureSource{Texture: texture, MinElevation: r[0], MaxElevation: r[1]},
+		Priority:  priority,
+		Feather:   *insertFeather,
+		MaxDetail: maxDetail,
+	}, nil
+}
+
+// radiusModel returns the range of a shape model's radii in meters.
+func radiusModel() (lo, hi float64, err error) {
+	r, err := parseFloats(*radiusRange, 2)
+	if err != nil {
+		return 0, 0, err
+	}
+	k := 1.0
+	if *radiusUnits == "km" {
+		k = 1000
+	}
+	return r[0] * k, r[1] * k, nil
+}
+
+func inputSource() (demsphere.ElevationSource, error) {
+	source, err := baseSource()
+	if err != nil || len(*inserts) == 0 {
+		return source, err
+	}
+	var layers []*demsphere.Insert
+	for i, spec := range *inserts {
+		insert, err := loadInsert(spec, i)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, insert)
+	}
+	return demsphere.NewLayers(source, layers...), nil
+}
+
+func baseSource() (demsphere.ElevationSource, error) {
+	if *harmonics != "" {
+		h, err := demsphere.LoadSphericalHarmonics(*harmonics)
+		if err != nil {
+			return nil, err
+		}
+		h.Degree = *harmonicsDegree
+		if *harmonicsUnits == "km" {
+			h.Scale = 1000
+		}
+		h.Offset = *harmonicsOffset
+		return h, nil
+	}
+	if *inputFile == "" {
+		return nil, fmt.Errorf("--input or --harmonics is required")
+	}
+	projection, err := inputProjection()
+	if err != nil {
+		return nil, err
+	}
+	texture, err := loadTexture(*inputFile, projection)
+	if err != nil {
+		return nil, err
+	}
+	if *radiusRange != "" {
+		lo, hi, err := radiusModel()
+		if err != nil {
+			return nil, err
+		}
+		return &demsphere.TextureSource{Texture: texture, MinElevation: lo, MaxElevation: hi}, nil
+	}
+	base := &demsphere.TextureSource{Texture: texture, MinElevation: float64(MinElevation), MaxElevation: float64(MaxElevation)}
+	if *northCap == "" && *southCap == "" {
+		return base, nil
+	}
+
+	lo, hi := float64(MinElevation), float64(MaxElevation)
+	if *capElevation != "" {
+		r, err := parseFloats(*capElevation, 2)
+		if err != nil {
+			return nil, err
+		}
+		lo, hi = r[0], r[1]
+	}
+	caps := &demsphere.PolarCaps{Base: base, Latitude: *capLatitude, Feather: *capFeather, EdgeFeather: *capEdgeFeather}
+	if caps.North, err = loadCap(*northCap, *northCapExtent, false, lo, hi); err != nil {
+		return nil, err
+	}
+	if caps.South, err = loadCap(*southCap, *southCapExtent, true, lo, hi); err != nil {
+		return nil, err
+	}
+	return caps, nil
+}
+
+func meshRegion() (*demsphere.Region, error) {
+	switch {
+	case *regionBounds != "" && *regionGeoJSON != "":
+		return nil, fmt.Errorf("--region-bounds and --region-geojson are mutually exclusive")
+	case *regionBounds != "":
+		b, err := parseFloats(*regionBounds, 4)
+		if err != nil {
+			return nil, err
+		}
+		return demsphere.NewBoundsRegion(b[0], b[1], b[2], b[3])
+	case *regionGeoJSON != "":
+		return demsphere.LoadGeoJSONRegion(*regionGeoJSON)
+	}
+	return nil, nil
+}
+
+func loadToleranceMap() (demsphere.ToleranceMap, error) {
+	if *toleranceMap != "" && len(*toleranceRegion) > 0 {
+		return nil, fmt.Errorf("--tolerance-map and --tolerance-region are mutually exclusive")
+	}
+	if *toleranceMap != "" {
+		parts := strings.Split(*toleranceMap, "@")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid tolerance map %q", *toleranceMap)
+		}
+		r, err := parseFloats(parts[1], 2)
+		if err != nil {
+			return nil, err
+		}
+		texture, err := loadTexture(parts[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		// --filter is for DEMs; a tolerance map needs no more than bilinear
+		texture.Filter = demsphere.Bilinear
+		return &demsphere.ToleranceRaster{Texture: texture, Min: r[0], Max: r[1]}, nil
+	}
+	var regions demsphere.ToleranceRegions
+	for _, spec := range *toleranceRegion {
+		parts := strings.Split(spec, "@")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid tolerance region %q", spec)
+		}
+		tolerance, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		maxDetail, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		var region *demsphere.Region
+		if b, err := parseFloats(parts[0], 4); err == nil {
+			region, err = demsphere.NewBoundsRegion(b[0], b[1], b[2], b[3])
+			if err != nil {
+				return nil, err
+			}
+		} else if region, err = demsphere.LoadGeoJSONRegion(parts[0]); err != nil {
+			return nil, err
+		}
+		regions = append(regions, demsphere.ToleranceRegion{Region: region, Tolerance: tolerance, MaxDetail: maxDetail})
+	}
+	if regions == nil {
+		return nil, nil
+	}
+	return regions, nil
+}
+
+func referenceEllipsoid() (*demsphere.Ellipsoid, error) {
+	if *ellipsoid == "" {
+		if *geodetic {
+			return nil, fmt.Errorf("--geodetic requires --ellipsoid")
+		}
+		return nil, nil
+	}
+	axes, err := parseFloats(*ellipsoid, strings.Count(*ellipsoid, ",")+1)
+	if err != nil {
+		return nil, err
+	}
+	switch len(axes) {
+	case 2:
+		return &demsphere.Ellipsoid{A: axes[0], B: axes[0], C: axes[1], Geodetic: *geodetic}, nil
+	case 3:
+		return &demsphere.Ellipsoid{A: axes[0], B: axes[1], C: axes[2], Geodetic: *geodetic}, nil
+	}
+	return nil, fmt.Errorf("invalid ellipsoid %q", *ellipsoid)
+}
+
+func loadBaseMesh(path string) ([]demsphere.Triangle, error) {
+	k := 1.0
+	if *baseMeshUnits == "km" {
+		k = 1000
+	}
+	return loadTriangles(path, k)
+}
+
+// loadTriangles reads an OBJ, STL or PLY mesh, scaling it by k.
+func loadTriangles(path string, k float64) ([]demsphere.Triangle, error) {
+	mesh, err := fauxgl.LoadMesh(path)
+	if err != nil {
+		return nil, err
+	}
+	vector := func(v fauxgl.Vector) demsphere.Vector {
+		return demsphere.Vector{X: v.X * k, Y: v.Y * k, Z: v.Z * k}
+	}
+	triangles := make([]demsphere.Triangle, len(mesh.Triangles))
+	for i, t := range mesh.Triangles {
+		triangles[i] = demsphere.Triangle{A: vector(t.V1.Position), B: vector(t.V2.Position), C: vector(t.V3.Position)}
+	}
+	return triangles, nil
+}
+
+func reliefProjection() (demsphere.Projection, error) {
+	switch *relief {
+	case "equirectangular", "mercator":
+		if *regionBounds == "" {
+			return nil, fmt.Errorf("--relief %s requires --region-bounds", *relief)
+		}
+		b, err := parseFloats(*regionBounds, 4)
+		if err != nil {
+			return nil, err
+		}
+		south, north, west, east := b[0], b[1], b[2], b[3]
+		if east < west {
+			east += 360
+		}
+		if *relief == "mercator" {
+			return demsphere.Mercator{Left: west, Right: east, Top: north, Bottom: south}, nil
+		}
+		return demsphere.Equirectangular{Left: west, Right: east, Top: north, Bottom: south}, nil
+	}
+	if *reliefExtent == "" {
+		return nil, fmt.Errorf("--relief %s requires --relief-extent", *relief)
+	}
+	e, err := parseFloats(*reliefExtent, 4)
+	if err != nil {
+		return nil, err
+	}
+	return demsphere.PolarStereographic{
+		South:             *relief == "south-polar",
+		Radius:            float64(MeanRadius),
+		CentralMeridian:   *centralMeridian,
+		TrueScaleLatitude: *trueScale,
+		MinX:              e[0], MaxX: e[1], MinY: e[2], MaxY: e[3],
+	}, nil
+}
+
+// describeTolerance shows the tolerance in its own space and its vertical
+// equivalent in every other space.
+func describeTolerance(tolerance, scale float64) string {
+	switch *metric {
+	case "normal":
+		return fmt.Sprintf("%g degrees", tolerance)
+	case "print":
+		return fmt.Sprintf("%g mm", tolerance)
+	}
+	source := tolerance
+	switch *toleranceSpace {
+	case "exaggerated":
+		source /= float64(Exaggeration)
+	case "output":
+		source /= float64(Exaggeration) * scale
+	}
+	return fmt.Sprintf("%g %s (%g source m, %g exaggerated m, %g output units)",
+		tolerance, *toleranceSpace, source, source*float64(Exaggeration), source*float64(Exaggeration)*scale)
+}
+
+// job holds the inputs shared by the commands that triangulate.
+type job struct {
+	source       demsphere.ElevationSource
+	base         []demsphere.Triangle
+	region       *demsphere.Region
+	body         *demsphere.Ellipsoid
+	toleranceMap demsphere.ToleranceMap
+	tolerance    float64
+	radius       float64
+	scale        float64
+}
+
+func newJob() (*job, error) {
+	source, err := inputSource()
+	if err != nil {
+		return nil, err
+	}
+	body, err := referenceEllipsoid()
+	if err != nil {
+		return nil, err
+	}
+	radius := float64(MeanRadius)
+	if *radiusRange != "" {
+		if body != nil || *relief != "" {
+			return nil, fmt.Errorf("--radius-model cannot be combined with --ellipsoid or --relief")
+		}
+		// Planet's radius says nothing about a shape model's size
+		if _, radius, err = radiusModel(); err != nil {
+			return nil, err
+		}
+	}
+	var base []demsphere.Triangle
+	if *baseMesh != "" {
+		if *radiusRange != "" || body != nil || *relief != "" {
+			return nil, fmt.Errorf("--base-mesh cannot be combined with --radius-model, --ellipsoid or --relief")
+		}
+		if base, err = loadBaseMesh(*baseMesh); err != nil {
+			return nil, err
+		}
+		// scale by the base mesh's extent rather than Planet's radius
+		radius = 0
+		for _, t := range base {
+			radius = max(radius, t.A.Length(), t.B.Length(), t.C.Length())
+		}
+		if radius == 0 {
+			return nil, fmt.Errorf("base mesh %s is empty", *baseMesh)
+		}
+	}
+	if *wallThickness < 0 || (*wallThickness > 0 && base == nil) {
+		return nil, fmt.Errorf("--wall-thickness must be positive and requires --base-mesh")
+	}
+	toleranceMap, err := loadToleranceMap()
+	if err != nil {
+		return nil, err
+	}
+	var region *demsphere.Region
+	if *relief == "" {
+		if region, err = meshRegion(); err != nil {
+			return nil, err
+		}
+		if *solid && region == nil {
+			return nil, fmt.Errorf("--solid requires --region-bounds, --region-geojson or --relief")
+		}
+	}
+	tolerance := float64(Tolerance)
+	if *metricTolerance > 0 {
+		tolerance = *metricTolerance
+	} else if *metric == "normal" || *metric == "print" {
+		// the mesh tolerance is in meters, not degrees or millimeters
+		return nil, fmt.Errorf("--metric %s requires --metric-tolerance", *metric)
+	}
+	j := &job{source, base, region, body, toleranceMap, tolerance, radius, 1 / radius}
+	if *autoDetail {
+		triangulator, err := j.triangulator(false)
+		if err != nil {
+			return nil, err
+		}
+		detail := triangulator.RecommendedMaxDetail()
+		if detail < 0 {
+			return nil, fmt.Errorf("--auto-detail requires a source of known resolution")
+		}
+		MaxDetail = detail
+		MinDetail = min(MinDetail, MaxDetail)
+	}
+	return j, nil
+}
+
+// print prints all the variables.
+func (j *job) print() {
+	output := fmt.Sprintf("\nPlanet: %s\nMinDetail: %d\nMaxDetail: %d\nMeanRadius: %d\nMinElevation: %d\nMaxElevation: %d\nTolerance: %s\nExaggeration: %d\nScale: %g\nInnerShellScale: %f\n",
+		Planet,
+		MinDetail,
+		MaxDetail,
+		MeanRadius,
+		MinElevation,
+		MaxElevation,
+		describeTolerance(j.tolerance, j.scale),
+		Exaggeration,
+		j.scale,
+		InnerShellScale,
+	)
+
+	fmt.Println(output)
+	j.printDetail()
+}
+
+// printDetail compares the triangle edge length at each detail level with
+// the DEM's pixel size.
+func (j *job) printDetail() {
+	triangulator, err := j.triangulator(false)
+	if err != nil {
+		return
+	}
+	resolution := demsphere.SourceResolution(j.source)
+	recommended := triangulator.RecommendedMaxDetail()
+	if recommended < 0 {
+		return
+	}
+	fmt.Printf("DEM resolution: %.0f m\n", resolution*j.radius)
+	for detail := min(MinDetail, recommended); detail <= max(MaxDetail, recommended); detail++ {
+		edge := triangulator.Footprint(detail)
+		note := ""
+		switch {
+		case detail == recommended && detail == MaxDetail:
+			note = " (MaxDetail, recommended)"
+		case detail == recommended:
+			note = " (recommended)"
+		case detail == MaxDetail:
+			note = " (MaxDetail)"
+		}
+		fmt.Printf("Detail %d: edge %.0f m, %.2f DEM pixels%s\n", detail, edge*j.radius, edge/resolution, note)
+	}
+	if MaxDetail < recommended {
+		fmt.Printf("MaxDetail %d undersamples the DEM; use %d or --auto-detail\n", MaxDetail, recommended)
+	} else if MaxDetail > recommended {
+		fmt.Printf("MaxDetail %d refines beyond the DEM's resolution; %d suffices\n", MaxDetail, recommended)
+	}
+	fmt.Println()
+}
+
+// offsetSource is a constant elevation, for the inner shell of a base mesh.
+type offsetSource float64
+
+func (s offsetSource) Elevation(spherical demsphere.Vector, footprint float64) float64 {
+	return float64(s)
+}
+
+// innerShell reports whether the mesh is closed by an inner shell. A base
+// mesh is already closed, and is only hollowed with a wall thickness.
+func (j *job) innerShell() bool {
+	return !*solid && *relief == "" && (j.base == nil || *wallThickness > 0)
+}
+
+// innerShare estimates the number of triangles in the inner shell for each
+// one in the outer shell at the job's tolerance. It can be well below one,
+// such as when the tolerance is in output units and the inner shell is
+// scaled down by InnerShellScale.
+func (j *job) innerShare(outer *demsphere.Triangulator) (float64, error) {
+	inner, err := j.triangulator(true)
+	if err != nil {
+		return 0, err
+	}
+	n := outer.Estimate(0.02).Triangles
+	if n == 0 {
+		return 1, nil
+	}
+	return float64(inner.Estimate(0.02).Triangles) / float64(n), nil
+}
+
+// triangulator returns the Triangulator for the outer shell, or for the
+// inner shell.
+func (j *job) triangulator(inner bool) (*demsphere.Triangulator, error) {
+	seeds := map[string]demsphere.Seed{
+		"icosahedron": demsphere.Icosahedron,
+		"octahedron":  demsphere.Octahedron,
+		"cube":        demsphere.CubeSphere,
+	}
+	refinements := map[string]demsphere.Refinement{
+		"quad":      demsphere.QuadSubdivision,
+		"bisection": demsphere.EdgeBisection,
+	}
+	metrics := map[string]demsphere.ErrorMetric{
+		"distance": nil,
+		"vertical": demsphere.MaxVerticalError{},
+		"rms":      demsphere.RMSError{},
+		"normal":   demsphere.NormalDeviation{},
+		"print":    demsphere.PrintError{MillimetersPerUnit: *mmPerUnit},
+	}
+	spaces := map[string]demsphere.ToleranceSpace{
+		"source":      demsphere.SourceMeters,
+		"exaggerated": demsphere.ExaggeratedMeters,
+		"output":      demsphere.OutputUnits,
+	}
+
+	source, tolerance, scale := j.source, j.tolerance, j.scale
+	var triangulator *demsphere.Triangulator
+	if inner && j.base != nil {
+		// scaling a body that is not star-shaped would move parts of the
+		// inner shell outside the outer one, so offset it along the normals
+		source = offsetSource(float64(MinElevation) - *wallThickness/float64(Exaggeration))
+	} else if inner {
+		scale *= InnerShellScale
+		if *radiusRange == "" {
+			source = &demsphere.Inverted{Source: source, MinElevation: float64(MinElevation), MaxElevation: float64(MaxElevation)}
+		}
+	}
+	if *relief != "" {
+		projection, err := reliefProjection()
+		if err != nil {
+			return nil, err
+		}
+		triangulator, err = demsphere.NewReliefTriangulator(
+			source, projection, int(MinDetail), int(MaxDetail), float64(MeanRadius), tolerance, float64(Exaggeration), scale)
+		if err != nil {
+			return nil, err
+		}
+	} else if j.base != nil {
+		triangulator = demsphere.NewMeshTriangulator(
+			j.base, source, int(MinDetail), int(MaxDetail), tolerance, float64(Exaggeration), scale)
+	} else if *radiusRange != "" {
+		// a shape model's inner shell is the same shape, scaled down
+		triangulator = demsphere.NewRadiusTriangulator(
+			source, int(MinDetail), int(MaxDetail), tolerance, scale)
+	} else {
+		triangulator = demsphere.NewSourceTriangulator(
+			source, int(MinDetail), int(MaxDetail), float64(MeanRadius), tolerance, float64(Exaggeration), scale)
+	}
+	triangulator.Mipmap = *mipmap
+	triangulator.Region = j.region
+	triangulator.Ellipsoid = j.body
+	triangulator.Seed = seeds[*seed]
+	triangulator.Refinement = refinements[*refinement]
+	triangulator.Metric = metrics[*metric]
+	triangulator.ToleranceSpace = spaces[*toleranceSpace]
+	triangulator.ToleranceMap = j.toleranceMap
+	return triangulator, nil
+}
+
+func main() {
+	switch kingpin.Parse() {
+	case estimateCommand.FullCommand():
+		estimate()
+	case infoCommand.FullCommand():
+		info()
+	case validateCommand.FullCommand():
+		validate()
+	default:
+		mesh()
+	}
+}
+
+func mesh() {
+	var done func()
+
+	j, err := newJob()
+	if err != nil {
+		log.Fatal(err)
+	}
+	j.print()
 
 	// Outer shell
-	triangulator := demsphere.NewTriangulator(
-	im, int(MinDetail), int(MaxDetail), float64(MeanRadius), float64(MinElevation), float64(MaxElevation), float64(Tolerance), float64(Exaggeration), float64(1/MeanRadius))
-	done = timed("Generating positive mesh")
-	triangles := triangulator.Triangulate()
-	done()
+	triangulator, err := j.triangulator(false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	target := *targetTriangles
+	if *targetSize > 0 {
+		// binary STL: 84 byte header, 50 bytes per triangle
+		target = int((*targetSize*1e6 - 84) / 50)
+	}
+	var triangles []demsphere.Triangle
+	if target > 0 {
+		if *solid && !*flatBase && *relief == "" {
+			// the curved base has as many triangles again
+			target /= 2
+		} else if j.innerShell() {
+			share, err := j.innerShare(triangulator)
+			if err != nil {
+				log.Fatal(err)
+			}
+			target = int(float64(target) / (1 + share))
+		}
+		done = timed("Searching for tolerance")
+		triangles = triangulator.TriangulateBudget(target, *targetMargin/100)
+		done()
+		j.tolerance = triangulator.Tolerance()
+		fmt.Printf("Chose tolerance %s\n", describeTolerance(j.tolerance, j.scale))
+	} else {
+		done = timed("Generating positive mesh")
+		triangles = triangulator.Triangulate()
+		done()
+	}
 	fmt.Println(fmt.Sprintf("Generated %v triangles for outer mesh", len(triangles)))
 
-	// Inner shell
-	im = imaging.Invert(im)
-	triangulator = demsphere.NewTriangulator(
-	im, int(MinDetail), int(MaxDetail), float64(MeanRadius), float64(MinElevation), float64(MaxElevation), float64(Tolerance), float64(Exaggeration), float64(1/MeanRadius)*float64(InnerShellScale))
+	if *accuracy || *heatmap != "" {
+		done = timed("Measuring accuracy")
+		a := triangulator.Accuracy(triangles, *accuracyDepth, *heatmapWidth)
+		done()
+		fmt.Printf("Vertical error over %d samples: max %.2f m, mean %.2f m, RMS %.2f m\n", a.Samples, a.Max, a.Mean, a.RMS)
+		fmt.Printf("Percentiles: 50%% %.2f m, 90%% %.2f m, 95%% %.2f m, 99%% %.2f m, 99.9%% %.2f m\n",
+			a.Percentile(50), a.Percentile(90), a.Percentile(95), a.Percentile(99), a.Percentile(99.9))
+		if *heatmap != "" {
+			if err := fauxgl.SavePNG(*heatmap, a.Heatmap(*heatmapLimit)); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	if *solid {
+		capSolid := demsphere.NewCapSolid(*baseDepth*j.scale, !*flatBase)
+		if *relief != "" {
+			capSolid.Curved = false
+			capSolid.Up = demsphere.Vector{Z: 1}
+		}
+		done = timed("Closing regional solid")
+		triangles, err = capSolid.Build(triangles)
+		done()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(fmt.Sprintf("Generated %v triangles for solid", len(triangles)))
+	} else if j.innerShell() {
+		// Inner shell
+		triangulator, err = j.triangulator(true)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	inner := triangulator.Triangulate()
-	for i, t := range inner {
-		inner[i] = demsphere.Triangle{t.C, t.B, t.A}
+		inner := triangulator.Triangulate()
+		for i, t := range inner {
+			inner[i] = demsphere.Triangle{A: t.C, B: t.B, C: t.A}
+		}
+		done = timed("Generating negative mesh")
+		triangles = append(triangles, inner...)
+		done()
+		fmt.Println(fmt.Sprintf("Generated %v triangles for inner mesh", len(triangles)))
 	}
-	done = timed("Generating negative mesh")
-	triangles = append(triangles, inner...)
-	done()
-	fmt.Println(fmt.Sprintf("Generated %v triangles for inner mesh", len(triangles)))
 
+	if *validateMesh {
+		done = timed("Validating mesh")
+		report := demsphere.Validate(triangles)
+		done()
+		printValidation(report)
+	}
 
 	// Filename
-	filename := fmt.Sprintf("%s_%d_%d_%d_%d.stl", Planet, MinDetail, MaxDetail, Tolerance, Exaggeration)
+	filename := fmt.Sprintf("%s_%d_%d_%g_%d.stl", Planet, MinDetail, MaxDetail, j.tolerance, Exaggeration)
 	fmt.Println(fmt.Sprintf("Filename set to %s", filename))
 	// Writing STL
 	done = timed("Writing output")
@@ -209,6 +961,42 @@ func main() {
 	done()
 }
 
+func estimate() {
+	j, err := newJob()
+	if err != nil {
+		log.Fatal(err)
+	}
+	j.print()
+
+	triangulator, err := j.triangulator(false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	done := timed("Sampling outer mesh")
+	e := triangulator.Estimate(*estimateSample)
+	done()
+	if *solid {
+		// walls are few; a curved base mirrors the surface
+		if !*flatBase && *relief == "" {
+			e = e.Add(demsphere.Estimate{Triangles: e.Triangles, Vertices: e.Vertices})
+		}
+	} else if j.innerShell() {
+		triangulator, err = j.triangulator(true)
+		if err != nil {
+			log.Fatal(err)
+		}
+		done = timed("Sampling inner mesh")
+		e = e.Add(triangulator.Estimate(*estimateSample))
+		done()
+	}
+
+	fmt.Printf("\nTriangles: %d\n", e.Triangles)
+	fmt.Printf("Vertices: %d\n", e.Vertices)
+	fmt.Printf("Peak memory: %.1f MB\n", float64(e.Memory)/1e6)
+	fmt.Printf("STL size: %.1f MB\n", float64(e.STLSize())/1e6)
+	fmt.Printf("Run time: %v\n", e.Duration.Round(time.Second/10))
+}
+
 // Planet data
 
 // Planet	Mean Radius (m)	Location	Max Elevation (m)	Min Depth (m)	Deepest Point Location
@@ -220,4 +1008,4 @@ func main() {
 // Saturn	58,232,000	N/A	N/A	N/A	N/A
 // Uranus	25,362,000	N/A	N/A	N/A	N/A
 // Neptune	24,622,000	N/A	N/A	N/A	N/A
-// Earth's Moon	1,737,100	Mons Huygens	10,800	9,000	Mare Imbrium
\ No newline at end of file
+// Earth's Moon	1,737,100	Mons Huygens	10,800	9,000	Mare Imbrium
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	demsphere "dem"
+)
+
+func printValidation(r *demsphere.ValidationReport) {
+	fmt.Printf("Triangles: %d\n", r.Triangles)
+	fmt.Printf("Vertices: %d\n", r.Vertices)
+	fmt.Printf("Shells: %d\n", r.Shells)
+	fmt.Printf("Boundary edges: %d\n", r.BoundaryEdges.Count)
+	fmt.Printf("Non-manifold edges: %d\n", r.NonManifoldEdges.Count)
+	fmt.Printf("Inconsistently wound edges: %d\n", r.InconsistentEdges.Count)
+	fmt.Printf("Degenerate triangles: %d\n", r.Degenerate.Count)
+	fmt.Printf("Duplicate triangles: %d\n", r.Duplicate.Count)
+	fmt.Printf("Intersecting shell triangles: %d\n", r.Intersections.Count)
+	if r.Valid() {
+		fmt.Println("Mesh is valid")
+	} else {
+		fmt.Println("Mesh is NOT valid")
+	}
+}
+
+func validate() {
+	done := timed(fmt.Sprintf("Reading %s", *validateFile))
+	triangles, err := loadTriangles(*validateFile, 1)
+	done()
+	if err != nil {
+		log.Fatal(err)
+	}
+	done = timed("Validating mesh")
+	report := demsphere.Validate(triangles)
+	done()
+	fmt.Println()
+	printValidation(report)
+
+	if *validateJSON != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		data = append(data, '\n')
+		if err := os.WriteFile(*validateJSON, data, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if !report.Valid() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,80 @@
+package demsphere
+
+import "math"
+
+// cubeToSphere returns the unit direction of a point on the surface of the
+// cube [-1, 1]³, whose coordinates on each face are proportional to angle.
+func cubeToSphere(k Vector) Vector {
+	const q = math.Pi / 4
+	return Vector{math.Tan(k.X * q), math.Tan(k.Y * q), math.Tan(k.Z * q)}.Normalize()
+}
+
+// sphereToCube is the inverse of cubeToSphere.
+func sphereToCube(d Vector) Vector {
+	m := math.Max(math.Abs(d.X), math.Max(math.Abs(d.Y), math.Abs(d.Z)))
+	k := func(t float64) float64 {
+		if math.Abs(t) == m {
+			return math.Copysign(1, t)
+		}
+		return math.Atan(t/m) * 4 / math.Pi
+	}
+	return Vector{k(d.X), k(d.Y), k(d.Z)}
+}
+
+// triangulateQuad refines a quad a, b, c, d into four on the same face until
+// both of its triangles a, b, c and a, c, d are accurate, and then leaves it
+// as those two triangles. The T-junctions between quads of different levels
+// are repaired like those between triangles.
+func (tri *Triangulator) triangulateQuad(detail int, class regionClass, a, b, c, d Vector) {
+	class1, class2 := class, class
+	if class == regionBoundary {
+		class1 = tri.classify(a, b, c)
+		class2 = tri.classify(a, c, d)
+		if class1 == regionOutside && class2 == regionOutside {
+			return
+		}
+		if class1 == regionInside && class2 == regionInside {
+			class = regionInside
+		}
+	}
+
+	if tri.sampling != nil && detail == tri.sampling.detail && !tri.sampling.take() {
+		return
+	}
+
+	maxDetail := max(tri.maxDetailFor(a, b, c), tri.maxDetailFor(a, c, d))
+	if detail >= maxDetail {
+		tri.leafQuad(detail, class1, class2, a, b, c, d)
+		return
+	}
+
+	if detail >= tri.minDetail && class != regionBoundary {
+		if tri.accurate(detail, maxDetail, a, b, c) && tri.accurate(detail, maxDetail, a, c, d) {
+			tri.leafQuad(detail, class1, class2, a, b, c, d)
+			return
+		}
+	}
+
+	ab := tri.domain.midpoint(a, b)
+	bc := tri.domain.midpoint(b, c)
+	cd := tri.domain.midpoint(c, d)
+	da := tri.domain.midpoint(d, a)
+	m := tri.domain.midpoint(ab, cd)
+
+	tri.triangulateQuad(detail+1, class, a, ab, m, da)
+	tri.triangulateQuad(detail+1, class, ab, b, bc, m)
+	tri.triangulateQuad(detail+1, class, m, bc, c, cd)
+	tri.triangulateQuad(detail+1, class, da, m, cd, d)
+}
+
+// leafQuad leaves the triangles of a quad that are not outside the Region.
+func (tri *Triangulator) leafQuad(detail int, class1, class2 regionClass, a, b, c, d Vector) {
+	if class1 != regionOutside {
+		tri.leaf(detail, class1 == regionBoundary, a, b, c)
+		tri.counts[detail]++
+	}
+	if class2 != regionOutside {
+		tri.leaf(detail, class2 == regionBoundary, a, c, d)
+		tri.counts[detail]++
+	}
+}
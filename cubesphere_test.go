@@ -0,0 +1,67 @@
+package demsphere
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCubeSphereKeys(t *testing.T) {
+	for _, k := range []Vector{
+		{1, 0, 0}, {-1, 0.5, -0.25}, {0.3, 1, -0.9}, {0.75, -0.125, -1}, {1, 1, 0.5}, {-1, -1, -1},
+	} {
+		if got := sphereToCube(cubeToSphere(k)); got.Sub(k).Length() > 1e-12 {
+			t.Errorf("%v round trips to %v", k, got)
+		}
+	}
+
+	// Equal steps across a face subtend equal angles.
+	angle := func(a, b float64) float64 {
+		return math.Acos(cubeToSphere(Vector{1, a, 0}).Dot(cubeToSphere(Vector{1, b, 0})))
+	}
+	for _, x := range []float64{-1, -0.5, 0, 0.5} {
+		if got := angle(x, x+0.5); math.Abs(got-math.Pi/8) > 1e-12 {
+			t.Errorf("%g to %g subtends %g", x, x+0.5, got)
+		}
+	}
+}
+
+// Every triangle of a cube-sphere lies on one face of the cube, and the
+// repaired mesh is closed where quads of different levels meet.
+func TestCubeSphereFaceLocal(t *testing.T) {
+	tri := NewSourceTriangulator(testRegionSource(), 1, 6, 6371000, 3000, 10, 1/6371000.0)
+	tri.Seed = CubeSphere
+	triangles := tri.Triangulate()
+	if r := Validate(triangles); !r.Valid() {
+		t.Errorf("%d boundary, %d non-manifold, %d inconsistent, %d degenerate, %d duplicate",
+			r.BoundaryEdges.Count, r.NonManifoldEdges.Count, r.InconsistentEdges.Count,
+			r.Degenerate.Count, r.Duplicate.Count)
+	}
+	levels := 0
+	for range tri.counts {
+		levels++
+	}
+	if levels < 2 {
+		t.Errorf("all quads were left at one level")
+	}
+	face := func(v Vector) [3]float64 {
+		var f [3]float64
+		for i, x := range [3]float64{v.X, v.Y, v.Z} {
+			if math.Abs(x) == 1 {
+				f[i] = x
+			}
+		}
+		return f
+	}
+	for _, l := range tri.temp {
+		a, b, c := face(l.A), face(l.B), face(l.C)
+		shared := false
+		for i := range a {
+			if a[i] != 0 && a[i] == b[i] && a[i] == c[i] {
+				shared = true
+			}
+		}
+		if !shared {
+			t.Fatalf("triangle %v spans faces", l)
+		}
+	}
+}
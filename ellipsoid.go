@@ -0,0 +1,54 @@
+package demsphere
+
+import "math"
+
+// Ellipsoid is a reference surface centered on the origin with semi-axes A
+// and B along X and Y in the equatorial plane and C along the polar axis, in
+// meters. A = B = C is a sphere and A = B > C an oblate spheroid.
+type Ellipsoid struct {
+	A, B, C float64
+
+	// Geodetic means that the DEM's latitudes and longitudes are those of
+	// the surface normal (planetographic) rather than of the direction from
+	// the center (planetocentric).
+	Geodetic bool
+}
+
+// Surface returns the point on the ellipsoid in the given unit direction
+// from its center.
+func (e *Ellipsoid) Surface(direction Vector) Vector {
+	x := direction.X / e.A
+	y := direction.Y / e.B
+	z := direction.Z / e.C
+	return direction.MulScalar(1 / math.Sqrt(x*x+y*y+z*z))
+}
+
+// Normal returns the outward unit normal at a point on the ellipsoid.
+func (e *Ellipsoid) Normal(p Vector) Vector {
+	return Vector{p.X / (e.A * e.A), p.Y / (e.B * e.B), p.Z / (e.C * e.C)}.Normalize()
+}
+
+// point returns the point at the given elevation along the normal above the
+// surface point in a direction.
+func (e *Ellipsoid) point(direction Vector, elevation float64) Vector {
+	p := e.Surface(direction)
+	return p.Add(e.Normal(p).MulScalar(elevation))
+}
+
+// direction returns the direction whose latitude and longitude match those
+// used by the DEM at the surface point in a direction.
+func (e *Ellipsoid) direction(direction Vector) Vector {
+	if !e.Geodetic {
+		return direction
+	}
+	return e.Normal(e.Surface(direction))
+}
+
+// key returns the direction of the surface point whose DEM direction is the
+// given one, the inverse of direction.
+func (e *Ellipsoid) key(direction Vector) Vector {
+	if !e.Geodetic {
+		return direction
+	}
+	return Vector{direction.X * e.A * e.A, direction.Y * e.B * e.B, direction.Z * e.C * e.C}.Normalize()
+}
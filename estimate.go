@@ -0,0 +1,130 @@
+package demsphere
+
+import (
+	"math"
+	"time"
+)
+
+// Approximate heap cost of the Triangulator's working state.
+const (
+	triangleBytes = 72  // one Triangle
+	vertexBytes   = 150 // one entry in each of the points and details maps
+)
+
+// Estimate is the projected size and cost of a triangulation.
+type Estimate struct {
+	Triangles int
+	Vertices  int
+	Memory    int64 // peak heap bytes, roughly
+	Duration  time.Duration
+}
+
+// STLSize returns the size in bytes of the estimated mesh as a binary STL.
+func (e Estimate) STLSize() int64 {
+	return 84 + 50*int64(e.Triangles)
+}
+
+// Add returns the combined estimate of two meshes built one after another.
+func (e Estimate) Add(f Estimate) Estimate {
+	return Estimate{
+		Triangles: e.Triangles + f.Triangles,
+		Vertices:  e.Vertices + f.Vertices,
+		Memory:    max(e.Memory, f.Memory) + triangleBytes*int64(min(e.Triangles, f.Triangles)),
+		Duration:  e.Duration + f.Duration,
+	}
+}
+
+// Estimate projects the result of Triangulate without building the whole
+// mesh. Refinement is followed exactly down to a sampling level, below which
+// only an evenly spread fraction of the triangles are refined further and
+// their results are scaled up. T-junctions along the edges of the samples
+// are not repaired as in the full mesh, so expect the projection to be off
+// by up to 10-15%. EdgeBisection refinement propagates between neighbours and
+// cannot be sampled, so it is estimated by a full pass.
+func (tri *Triangulator) Estimate(fraction float64) Estimate {
+	start := time.Now()
+	var textureBytes int64
+	if tri.Refinement == EdgeBisection || fraction >= 1 {
+		tri.Triangulate()
+		textureBytes = tri.textureBytes()
+		return tri.estimate(1, textureBytes, time.Since(start))
+	}
+
+	stride := max(int(math.Round(1/fraction)), 1)
+	tri.prepare()
+	setup := time.Since(start)
+	defer func() { tri.sampling = nil }()
+	run := func(s *sampling) {
+		class := tri.prepare()
+		tri.sampling = s
+		tri.triangulateSeeds(class)
+	}
+	// find the shallowest level that a few hundred samples can be drawn from
+	level := 0
+	for ; level < tri.maxDetail; level++ {
+		s := &sampling{detail: level}
+		run(s)
+		if s.n >= 256*stride {
+			break
+		}
+	}
+	start = time.Now()
+	run(&sampling{detail: level, stride: stride, n: stride / 2})
+	tri.repair()
+	elapsed := time.Since(start)
+	textureBytes = tri.textureBytes()
+
+	var exact, sampled int
+	for d, n := range tri.counts {
+		if d < tri.sampling.detail {
+			exact += n
+		} else {
+			sampled += n
+		}
+	}
+	if exact+sampled == 0 {
+		return Estimate{Memory: textureBytes, Duration: setup}
+	}
+	k := float64(exact+stride*sampled) / float64(exact+sampled)
+	e := tri.estimate(k, textureBytes, elapsed)
+	e.Duration += setup
+	return e
+}
+
+// sampling selects every stride'th triangle reaching a detail level for
+// refinement while estimating.
+type sampling struct {
+	detail int
+	stride int
+	n      int
+}
+
+// take counts a triangle reaching the level and reports whether to refine
+// it. A zero stride only counts.
+func (s *sampling) take() bool {
+	s.n++
+	return s.stride > 0 && s.n%s.stride == 0
+}
+
+func (tri *Triangulator) textureBytes() int64 {
+	var result int64
+	for _, t := range sourceTextures(tri.source) {
+		n := int64(len(t.Pix)) * 8
+		if t.mipmaps != nil {
+			n += n / 3
+		}
+		result += n + int64(len(t.Valid))
+	}
+	return result
+}
+
+// estimate scales the state of a sampled triangulation by k.
+func (tri *Triangulator) estimate(k float64, textureBytes int64, elapsed time.Duration) Estimate {
+	triangles := int(math.Round(k * float64(len(tri.triangles))))
+	// samples share no vertices, so count those of a closed mesh instead
+	vertices := triangles/2 + 2
+	leaves := int(math.Round(k * float64(len(tri.temp)+len(tri.boundary))))
+	// slices may hold twice their length while growing
+	memory := textureBytes + 2*triangleBytes*int64(triangles+leaves) + vertexBytes*int64(vertices)
+	return Estimate{triangles, vertices, memory, time.Duration(k * float64(elapsed))}
+}
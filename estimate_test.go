@@ -0,0 +1,60 @@
+package demsphere
+
+import "testing"
+
+// A sampled estimate lands within the 15% that Estimate documents, and a
+// full pass is exact.
+func TestEstimateTriangles(t *testing.T) {
+	for _, test := range []struct {
+		seed       Seed
+		refinement Refinement
+		fraction   float64
+		within     float64
+	}{
+		{Icosahedron, QuadSubdivision, 0.05, 0.15},
+		{Octahedron, QuadSubdivision, 0.05, 0.15},
+		{CubeSphere, QuadSubdivision, 0.05, 0.15},
+		{Icosahedron, QuadSubdivision, 1, 0},
+		{Icosahedron, EdgeBisection, 0.05, 0},
+	} {
+		tri := NewSourceTriangulator(testRegionSource(), 2, 7, 6371000, 1000, 1, 1/6371000.0)
+		tri.Seed = test.seed
+		tri.Refinement = test.refinement
+		e := tri.Estimate(test.fraction)
+		n := len(tri.Triangulate())
+		if d := float64(e.Triangles-n) / float64(n); d < -test.within || d > test.within {
+			t.Errorf("%+v: estimated %d triangles, got %d", test, e.Triangles, n)
+		}
+		if e.Vertices != e.Triangles/2+2 {
+			t.Errorf("%+v: %d vertices for %d triangles of a closed mesh", test, e.Vertices, e.Triangles)
+		}
+		if e.STLSize() != 84+50*int64(e.Triangles) {
+			t.Errorf("%+v: STL size %d for %d triangles", test, e.STLSize(), e.Triangles)
+		}
+	}
+}
+
+// A region is sampled like the rest of the sphere.
+func TestEstimateRegion(t *testing.T) {
+	region, err := NewBoundsRegion(-20, 40, 10, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tri := NewSourceTriangulator(testRegionSource(), 2, 8, 6371000, 1000, 1, 1/6371000.0)
+	tri.Region = region
+	e := tri.Estimate(0.05)
+	n := len(tri.Triangulate())
+	if d := float64(e.Triangles-n) / float64(n); d < -0.15 || d > 0.15 {
+		t.Errorf("estimated %d triangles, got %d", e.Triangles, n)
+	}
+}
+
+func TestEstimateAdd(t *testing.T) {
+	a := Estimate{Triangles: 100, Vertices: 52, Memory: 1000, Duration: 3}
+	b := Estimate{Triangles: 40, Vertices: 22, Memory: 2000, Duration: 4}
+	got := a.Add(b)
+	want := Estimate{Triangles: 140, Vertices: 74, Memory: 2000 + 40*triangleBytes, Duration: 7}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
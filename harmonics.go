@@ -0,0 +1,175 @@
+package demsphere
+
+import (
+	"bufio"
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// harmonicsScale keeps the scaled Legendre functions of very high degrees
+// from underflowing near the poles (Holmes and Featherstone, 2002).
+const harmonicsScale = 1e-280
+
+// SphericalHarmonics is an ElevationSource evaluated from a real spherical
+// harmonic expansion with 4-pi (geodesy) normalized coefficients. Each
+// sample costs time proportional to the square of the degree.
+type SphericalHarmonics struct {
+	// Degree truncates the expansion for a smoother, faster surface. Zero
+	// means every available degree.
+	Degree int
+
+	// Scale converts the expansion's units to meters, e.g. 1000 for km.
+	Scale float64
+
+	// Offset, in meters, is subtracted from the result, e.g. the reference
+	// radius of a shape model whose C00 term is the mean radius.
+	Offset float64
+
+	degree int
+	c, s   []float64
+	a, b   []float64
+}
+
+// NewSphericalHarmonics returns an expansion with the given coefficients,
+// indexed C[l][m] for degree l and order m.
+func NewSphericalHarmonics(c, s [][]float64) *SphericalHarmonics {
+	degree := len(c) - 1
+	h := &SphericalHarmonics{Scale: 1, degree: degree}
+	n := (degree + 1) * (degree + 2) / 2
+	h.c = make([]float64, n)
+	h.s = make([]float64, n)
+	for l := range c {
+		for m := 0; m <= l && m < len(c[l]); m++ {
+			h.c[harmonicIndex(l, m)] = c[l][m]
+			if l < len(s) && m < len(s[l]) {
+				h.s[harmonicIndex(l, m)] = s[l][m]
+			}
+		}
+	}
+	h.a = make([]float64, n)
+	h.b = make([]float64, n)
+	for m := 0; m <= degree; m++ {
+		for l := m + 2; l <= degree; l++ {
+			fl, fm := float64(l), float64(m)
+			i := harmonicIndex(l, m)
+			h.a[i] = math.Sqrt((2*fl - 1) * (2*fl + 1) / ((fl - fm) * (fl + fm)))
+			h.b[i] = math.Sqrt((2*fl + 1) * (fl + fm - 1) * (fl - fm - 1) / ((fl - fm) * (fl + fm) * (2*fl - 3)))
+		}
+	}
+	return h
+}
+
+// LoadSphericalHarmonics reads a coefficient table with one
+// "degree order C S" row per line, separated by spaces or commas, as in
+// SHTOOLS and PDS SHADR files. Extra columns, such as uncertainties, and
+// lines that are not coefficient rows, such as headers, are ignored.
+func LoadSphericalHarmonics(path string) (*SphericalHarmonics, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var c, s [][]float64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.FieldsFunc(scanner.Text(), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) < 4 {
+			continue
+		}
+		l, err1 := strconv.Atoi(fields[0])
+		m, err2 := strconv.Atoi(fields[1])
+		cv, err3 := strconv.ParseFloat(strings.Replace(fields[2], "D", "E", 1), 64)
+		sv, err4 := strconv.ParseFloat(strings.Replace(fields[3], "D", "E", 1), 64)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || l < 0 || m < 0 || m > l {
+			continue
+		}
+		for len(c) <= l {
+			c = append(c, make([]float64, len(c)+1))
+			s = append(s, make([]float64, len(s)+1))
+		}
+		c[l][m] = cv
+		s[l][m] = sv
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(c) == 0 {
+		return nil, errors.New("no spherical harmonic coefficients found")
+	}
+	return NewSphericalHarmonics(c, s), nil
+}
+
+func harmonicIndex(l, m int) int {
+	return l*(l+1)/2 + m
+}
+
+// MaxDegree returns the highest degree of the expansion.
+func (h *SphericalHarmonics) MaxDegree() int {
+	return h.degree
+}
+
+// Elevation evaluates the expansion in a direction. A nonzero footprint
+// truncates it further to the degrees that the footprint can resolve.
+func (h *SphericalHarmonics) Elevation(spherical Vector, footprint float64) float64 {
+	degree := h.degree
+	if h.Degree > 0 && h.Degree < degree {
+		degree = h.Degree
+	}
+	if footprint > 0 {
+		degree = min(degree, max(1, int(math.Pi/footprint)))
+	}
+	return h.evaluate(spherical, degree)*h.Scale - h.Offset
+}
+
+// evaluate sums the expansion up to a degree. The Legendre functions are
+// computed divided by cos(latitude)^m, scaled by harmonicsScale, with the
+// powers of cos(latitude) applied by Horner's rule over the orders.
+func (h *SphericalHarmonics) evaluate(spherical Vector, degree int) float64 {
+	t := clamp(spherical.Z, -1, 1)
+	u := math.Hypot(spherical.X, spherical.Y)
+	cosl, sinl := 1.0, 0.0
+	if u > 0 {
+		cosl, sinl = spherical.X/u, spherical.Y/u
+	}
+
+	orders := make([]float64, degree+1)
+	pmm := harmonicsScale
+	cosm, sinm := 1.0, 0.0
+	for m := 0; m <= degree; m++ {
+		if m == 1 {
+			pmm *= math.Sqrt(3)
+		} else if m > 1 {
+			pmm *= math.Sqrt(float64(2*m+1) / float64(2*m))
+		}
+		i := harmonicIndex(m, m)
+		sum := pmm * (h.c[i]*cosm + h.s[i]*sinm)
+		var c, s float64
+		p2, p1 := 0.0, pmm
+		for l := m + 1; l <= degree; l++ {
+			i = harmonicIndex(l, m)
+			var p float64
+			if l == m+1 {
+				p = math.Sqrt(float64(2*m+3)) * t * p1
+			} else {
+				p = h.a[i]*t*p1 - h.b[i]*p2
+			}
+			c += p * h.c[i]
+			s += p * h.s[i]
+			p2, p1 = p1, p
+		}
+		orders[m] = sum + c*cosm + s*sinm
+		cosm, sinm = cosm*cosl-sinm*sinl, sinm*cosl+cosm*sinl
+	}
+
+	var result float64
+	for m := degree; m >= 0; m-- {
+		result = result*u + orders[m]
+	}
+	return result / harmonicsScale
+}
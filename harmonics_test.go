@@ -0,0 +1,119 @@
+package demsphere
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// legendre3 returns the 4-pi normalized associated Legendre functions up to
+// degree 3 in closed form, indexed by harmonicIndex.
+func legendre3(t float64) []float64 {
+	u := math.Sqrt(1 - t*t)
+	return []float64{
+		1,
+		math.Sqrt(3) * t,
+		math.Sqrt(3) * u,
+		math.Sqrt(5) * (3*t*t - 1) / 2,
+		math.Sqrt(15) * t * u,
+		math.Sqrt(15) / 2 * u * u,
+		math.Sqrt(7) * (5*t*t*t - 3*t) / 2,
+		math.Sqrt(7.0/6) * 1.5 * u * (5*t*t - 1),
+		math.Sqrt(7.0/60) * 15 * t * u * u,
+		math.Sqrt(7.0/360) * 15 * u * u * u,
+	}
+}
+
+func TestSphericalHarmonicsDegree3(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	c := make([][]float64, 4)
+	s := make([][]float64, 4)
+	for l := range c {
+		c[l] = make([]float64, l+1)
+		s[l] = make([]float64, l+1)
+		for m := range c[l] {
+			c[l][m] = r.NormFloat64()
+			if m > 0 {
+				s[l][m] = r.NormFloat64()
+			}
+		}
+	}
+	h := NewSphericalHarmonics(c, s)
+	for _, lat := range []float64{-90, -61, -20, 0, 7, 45, 83, 90} {
+		for _, lng := range []float64{-170, -45, 0, 30, 123} {
+			p := legendre3(math.Sin(radians(lat)))
+			var want float64
+			for l := 0; l <= 3; l++ {
+				for m := 0; m <= l; m++ {
+					fm := float64(m) * radians(lng)
+					want += p[harmonicIndex(l, m)] * (c[l][m]*math.Cos(fm) + s[l][m]*math.Sin(fm))
+				}
+			}
+			got := h.Elevation(LatLngToVector(lat, lng), 0)
+			if math.Abs(got-want) > 1e-12*math.Max(1, math.Abs(want)) {
+				t.Errorf("%g, %g: got %g, want %g", lat, lng, got, want)
+			}
+		}
+	}
+}
+
+// At degree 2600 the sectoral functions fall far below the smallest float64
+// near the poles, which harmonicsScale must absorb.
+func TestSphericalHarmonicsHighDegree(t *testing.T) {
+	const degree = 2600
+	c := make([][]float64, degree+1)
+	for l := range c {
+		c[l] = make([]float64, l+1)
+	}
+
+	// A zonal term is sqrt(2l+1) times the Legendre polynomial, which is 1
+	// at the poles and a product of odd over even numbers at the equator.
+	c[degree][0] = 1
+	h := NewSphericalHarmonics(c, nil)
+	norm := math.Sqrt(2*degree + 1)
+	equator := norm
+	for k := 1; k <= degree/2; k++ {
+		equator *= float64(2*k-1) / float64(2*k)
+	}
+	for _, test := range []struct{ lat, want float64 }{{90, norm}, {-90, norm}, {0, equator}} {
+		got := h.Elevation(LatLngToVector(test.lat, 10), 0)
+		if math.Abs(got-test.want) > 1e-9*test.want {
+			t.Errorf("zonal at %g: got %g, want %g", test.lat, got, test.want)
+		}
+	}
+
+	// A sectoral term is a product of square roots times cos(lat)^l, which
+	// is around 1e-160 at 30 degrees, where it must come out negligible
+	// rather than exact.
+	c[degree][0] = 0
+	c[degree][degree] = 1
+	h = NewSphericalHarmonics(c, nil)
+	for _, lat := range []float64{0, 30, -30} {
+		log := 0.5*math.Log(3) + degree*math.Log(math.Cos(radians(lat)))
+		for m := 2; m <= degree; m++ {
+			log += 0.5 * math.Log(float64(2*m+1)/float64(2*m))
+		}
+		want := math.Exp(log) * math.Cos(degree*radians(1.25))
+		got := h.Elevation(LatLngToVector(lat, 1.25), 0)
+		if math.Abs(got-want) > 1e-9*math.Max(1, math.Abs(want)) {
+			t.Errorf("sectoral at %g: got %g, want %g", lat, got, want)
+		}
+	}
+
+	// With every coefficient set, the result stays finite up to the poles.
+	r := rand.New(rand.NewSource(1))
+	s := make([][]float64, degree+1)
+	for l := range c {
+		s[l] = make([]float64, l+1)
+		for m := range c[l] {
+			c[l][m] = r.NormFloat64()
+			s[l][m] = r.NormFloat64()
+		}
+	}
+	h = NewSphericalHarmonics(c, s)
+	for _, lat := range []float64{-90, -89.99, -60, 0, 45, 89.9, 90} {
+		if e := h.Elevation(LatLngToVector(lat, 77), 0); math.IsNaN(e) || math.IsInf(e, 0) {
+			t.Errorf("%g: got %g", lat, e)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package demsphere
+
+import (
+	"math"
+	"sort"
+)
+
+// DetailSource is implemented by sources that need finer triangles than the
+// Triangulator's maximum detail level in some regions.
+type DetailSource interface {
+	ElevationSource
+
+	// MaxDetail returns the deepest detail level that a triangle with the
+	// given unit vertices may be refined to, or zero for no preference.
+	MaxDetail(v1, v2, v3 Vector) int
+}
+
+// Insert is a regional, typically high resolution, DEM layered over a
+// global source.
+type Insert struct {
+	Source *TextureSource
+
+	// Priority orders overlapping inserts; higher priorities are on top.
+	Priority int
+
+	// Feather is the width, in pixels of the insert, of the band inside its
+	// edge over which it blends into the layers beneath.
+	Feather float64
+
+	// MaxDetail is the detail level that triangles overlapping the insert
+	// may be refined to.
+	MaxDetail int
+
+	center Vector
+	radius float64
+}
+
+func (in *Insert) weight(spherical Vector) float64 {
+	return edgeWeight(in.Source.Texture, spherical, in.Feather)
+}
+
+// Layers stacks regional inserts over a base source.
+type Layers struct {
+	Base    ElevationSource
+	Inserts []*Insert
+}
+
+// NewLayers returns a Layers with the inserts ordered from highest to lowest
+// priority, keeping the given order among equal priorities.
+func NewLayers(base ElevationSource, inserts ...*Insert) *Layers {
+	inserts = append([]*Insert(nil), inserts...)
+	sort.SliceStable(inserts, func(i, j int) bool {
+		return inserts[i].Priority > inserts[j].Priority
+	})
+	for _, in := range inserts {
+		in.center, in.radius = in.Source.Texture.Bounds()
+	}
+	return &Layers{base, inserts}
+}
+
+// Elevation composites the inserts from the top down, each covering the
+// fraction of the result left uncovered by those above it.
+func (l *Layers) Elevation(spherical Vector, footprint float64) float64 {
+	var e float64
+	remaining := 1.0
+	for _, in := range l.Inserts {
+		w := in.weight(spherical)
+		if w == 0 {
+			continue
+		}
+		e += remaining * w * in.Source.Elevation(spherical, footprint)
+		remaining *= 1 - w
+		if remaining == 0 {
+			return e
+		}
+	}
+	return e + remaining*l.Base.Elevation(spherical, footprint)
+}
+
+// MaxDetail returns the deepest MaxDetail among the inserts whose bounding
+// cap overlaps the triangle's.
+func (l *Layers) MaxDetail(v1, v2, v3 Vector) int {
+	center := v1.Add(v2).Add(v3).Normalize()
+	radius := math.Max(angle(center, v1), math.Max(angle(center, v2), angle(center, v3)))
+	var result int
+	for _, in := range l.Inserts {
+		if in.MaxDetail > result && angle(center, in.center) < radius+in.radius {
+			result = in.MaxDetail
+		}
+	}
+	return result
+}
@@ -0,0 +1,149 @@
+package demsphere
+
+import "math"
+
+// ErrorMetric measures how far a triangle deviates from the surface that it
+// approximates. A Triangulator accepts a triangle when its error is at most
+// the tolerance, which is in the metric's own units. A nil metric measures
+// the maximum distance of the sampled surface from the triangle's plane in
+// the tolerance space, stopping at the first sample that exceeds the
+// tolerance, which is considerably faster than sampling the whole grid as
+// the metrics do.
+type ErrorMetric interface {
+	Error(samples *ErrorSamples) float64
+}
+
+// ErrorSamples is the surface sampled over a triangle.
+type ErrorSamples struct {
+	// Triangle is the triangle on the surface in the Triangulator's
+	// tolerance space, meters by default, and Output the same triangle
+	// exaggerated and scaled.
+	Triangle, Output Triangle
+
+	// Points are surface points sampled on a grid over the triangle.
+	Points []ErrorPoint
+
+	// Cells are the triangles of the grid in the tolerance space.
+	Cells []Triangle
+}
+
+// ErrorPoint is a sampled surface point.
+type ErrorPoint struct {
+	// Surface is the surface point in the tolerance space and Output the
+	// same point exaggerated and scaled.
+	Surface, Output Vector
+
+	// Up is the unit vertical direction at the point.
+	Up Vector
+}
+
+// minVerticalCosine bounds the cosine between a triangle's normal and the
+// vertical in MaxVerticalError, so that a nearly vertical triangle counts at
+// most 100 times its distance from the surface rather than infinitely far.
+const minVerticalCosine = 0.01
+
+// MaxVerticalError is the largest distance, measured vertically, between the
+// sampled surface and the triangle.
+type MaxVerticalError struct{}
+
+func (MaxVerticalError) Error(s *ErrorSamples) float64 {
+	plane := MakePlane(s.Triangle.A, s.Triangle.B, s.Triangle.C)
+	var result float64
+	for _, p := range s.Points {
+		d := plane.DistanceToPoint(p.Surface) / math.Max(math.Abs(plane.N.Dot(p.Up)), minVerticalCosine)
+		result = math.Max(result, d)
+	}
+	return result
+}
+
+// RMSError is the root mean square distance between the sampled surface and
+// the triangle's plane. It tolerates isolated spikes that MaxVerticalError
+// would refine around.
+type RMSError struct{}
+
+func (RMSError) Error(s *ErrorSamples) float64 {
+	plane := MakePlane(s.Triangle.A, s.Triangle.B, s.Triangle.C)
+	var sum float64
+	for _, p := range s.Points {
+		d := plane.DistanceToPoint(p.Surface)
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(s.Points)))
+}
+
+// NormalDeviation is the largest angle in degrees between the normal of the
+// sampled surface and the triangle's normal, which governs how smoothly the
+// mesh shades.
+type NormalDeviation struct{}
+
+func (NormalDeviation) Error(s *ErrorSamples) float64 {
+	n := s.Triangle.Normal()
+	var result float64
+	for _, c := range s.Cells {
+		d := clamp(c.Normal().Dot(n), -1, 1)
+		result = math.Max(result, degrees(math.Acos(d)))
+	}
+	return result
+}
+
+// PrintError is the largest distance in millimeters between the sampled
+// surface and the triangle on the print, after exaggeration and scaling.
+// MillimetersPerUnit converts output units to millimeters; zero means the
+// output is in millimeters.
+type PrintError struct {
+	MillimetersPerUnit float64
+}
+
+func (m PrintError) Error(s *ErrorSamples) float64 {
+	plane := MakePlane(s.Output.A, s.Output.B, s.Output.C)
+	var result float64
+	for _, p := range s.Points {
+		result = math.Max(result, plane.DistanceToPoint(p.Output))
+	}
+	if m.MillimetersPerUnit > 0 {
+		result *= m.MillimetersPerUnit
+	}
+	return result
+}
+
+// sampleError samples the surface on a grid over a triangle, subdivided
+// depth times, and measures it with the Triangulator's metric.
+func (tri *Triangulator) sampleError(detail, depth int, v1, v2, v3 Vector) float64 {
+	s := &ErrorSamples{}
+	index := make(map[Vector]int)
+	sample := func(v Vector) ErrorPoint {
+		if i, ok := index[v]; ok {
+			return s.Points[i]
+		}
+		e := tri.elevation(v, detail)
+		p := tri.toleranceSpace(v, e)
+		q := ErrorPoint{
+			Surface: p,
+			Output:  tri.domain.point(v, e*tri.exaggeration).MulScalar(tri.scale),
+			Up:      tri.toleranceSpace(v, e+1).Sub(p).Normalize(),
+		}
+		index[v] = len(s.Points)
+		s.Points = append(s.Points, q)
+		return q
+	}
+	var cells func(depth int, v1, v2, v3 Vector)
+	cells = func(depth int, v1, v2, v3 Vector) {
+		if depth == 0 {
+			p1, p2, p3 := sample(v1), sample(v2), sample(v3)
+			s.Cells = append(s.Cells, Triangle{p1.Surface, p2.Surface, p3.Surface})
+			return
+		}
+		v12 := tri.domain.sampleMidpoint(v1, v2)
+		v23 := tri.domain.sampleMidpoint(v2, v3)
+		v31 := tri.domain.sampleMidpoint(v3, v1)
+		cells(depth-1, v1, v12, v31)
+		cells(depth-1, v2, v23, v12)
+		cells(depth-1, v3, v31, v23)
+		cells(depth-1, v12, v23, v31)
+	}
+	p1, p2, p3 := sample(v1), sample(v2), sample(v3)
+	s.Triangle = Triangle{p1.Surface, p2.Surface, p3.Surface}
+	s.Output = Triangle{p1.Output, p2.Output, p3.Output}
+	cells(depth, v1, v2, v3)
+	return tri.Metric.Error(s)
+}
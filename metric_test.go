@@ -0,0 +1,93 @@
+package demsphere
+
+import (
+	"math"
+	"testing"
+)
+
+// testErrorSamples returns samples over a triangle in the plane z = 0, the
+// given heights above it with vertical Up, and the output scaled by 2.
+func testErrorSamples(up Vector, heights ...float64) *ErrorSamples {
+	t := Triangle{Vector{0, 0, 0}, Vector{1, 0, 0}, Vector{0, 1, 0}}
+	s := &ErrorSamples{
+		Triangle: t,
+		Output:   Triangle{t.A.MulScalar(2), t.B.MulScalar(2), t.C.MulScalar(2)},
+	}
+	for i, h := range heights {
+		p := Vector{0.1 * float64(i), 0.1, h}
+		s.Points = append(s.Points, ErrorPoint{Surface: p, Output: p.MulScalar(2), Up: up})
+	}
+	return s
+}
+
+func TestMaxVerticalError(t *testing.T) {
+	up := Vector{0, 0, 1}
+	if got := (MaxVerticalError{}).Error(testErrorSamples(up, 1, -3, 2)); got != 3 {
+		t.Errorf("vertical surface: got %g, want 3", got)
+	}
+
+	// A triangle tilted 60 degrees from the vertical is three times as far
+	// vertically as it is perpendicularly.
+	tilted := Vector{math.Sqrt(8), 0, 1}.Normalize()
+	if got := (MaxVerticalError{}).Error(testErrorSamples(tilted, 1)); math.Abs(got-3) > 1e-12 {
+		t.Errorf("tilted: got %g, want 3", got)
+	}
+
+	// A vertical triangle is far but finitely so.
+	got := (MaxVerticalError{}).Error(testErrorSamples(Vector{1, 0, 0}, 1))
+	if got != 1/minVerticalCosine {
+		t.Errorf("vertical triangle: got %g, want %g", got, 1/minVerticalCosine)
+	}
+}
+
+func TestRMSError(t *testing.T) {
+	got := (RMSError{}).Error(testErrorSamples(Vector{0, 0, 1}, 3, -4, 0, 0))
+	if want := math.Sqrt(25.0 / 4); math.Abs(got-want) > 1e-12 {
+		t.Errorf("got %g, want %g", got, want)
+	}
+}
+
+func TestNormalDeviation(t *testing.T) {
+	s := testErrorSamples(Vector{0, 0, 1})
+	s.Cells = []Triangle{
+		s.Triangle,
+		{Vector{0, 0, 0}, Vector{1, 0, math.Tan(radians(30))}, Vector{0, 1, 0}},
+	}
+	if got := (NormalDeviation{}).Error(s); math.Abs(got-30) > 1e-9 {
+		t.Errorf("got %g, want 30", got)
+	}
+}
+
+func TestPrintError(t *testing.T) {
+	s := testErrorSamples(Vector{0, 0, 1}, 0.5, -1.5)
+	if got := (PrintError{}).Error(s); got != 3 {
+		t.Errorf("got %g, want 3", got)
+	}
+	if got := (PrintError{MillimetersPerUnit: 10}).Error(s); got != 30 {
+		t.Errorf("10 mm per unit: got %g, want 30", got)
+	}
+}
+
+// Each metric refines further as its tolerance tightens.
+func TestMetricsRefine(t *testing.T) {
+	tests := []struct {
+		metric      ErrorMetric
+		loose, fine float64
+	}{
+		{MaxVerticalError{}, 5000, 500},
+		{RMSError{}, 20000, 2000},
+		{NormalDeviation{}, 40, 10},
+		{PrintError{}, 0.005, 0.0005},
+	}
+	for _, test := range tests {
+		var counts [2]int
+		for i, tolerance := range []float64{test.loose, test.fine} {
+			tri := NewSourceTriangulator(testRegionSource(), 1, 5, 6371000, tolerance, 1, 1/6371000.0)
+			tri.Metric = test.metric
+			counts[i] = len(tri.Triangulate())
+		}
+		if counts[0] >= counts[1] {
+			t.Errorf("%T: %d triangles at %g, %d at %g", test.metric, counts[0], test.loose, counts[1], test.fine)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package demsphere
+
+import "math"
+
+// BuildMipmaps builds a pyramid of successively half-resolution textures.
+// Each level is area averaged. Rows of equirectangular textures are weighted
+// by the cosine of their latitude so that the oversampled polar rows do not
+// dominate.
+func (t *Texture) BuildMipmaps() {
+	t.mipmaps = nil
+	level := t
+	for level.W > 1 || level.H > 1 {
+		level = level.downsample()
+		t.mipmaps = append(t.mipmaps, level)
+	}
+}
+
+func (t *Texture) downsample() *Texture {
+	w := max(t.W/2, 1)
+	h := max(t.H/2, 1)
+	xs := boxWeights(t.W, w)
+	ys := boxWeights(t.H, h)
+	rows := t.rowWeights()
+	pix := make([]float64, w*h)
+	for y, ry := range ys {
+		for x, rx := range xs {
+			var sum, total float64
+			for _, sy := range ry {
+				wy := sy.w * rows[sy.i]
+				for _, sx := range rx {
+					w := sx.w * wy
+					sum += t.Pix[sx.i+sy.i*t.W] * w
+					total += w
+				}
+			}
+			pix[x+y*w] = sum / total
+		}
+	}
+	m := &Texture{W: w, H: h, Pix: pix, Filter: t.Filter, Projection: t.Projection}
+	m.UpdatePoles()
+	return m
+}
+
+// rowWeights returns the relative area of a pixel in each row.
+func (t *Texture) rowWeights() []float64 {
+	p, ok := t.Projection.(Equirectangular)
+	if t.Projection == nil {
+		p, ok = GlobalEquirectangular, true
+	}
+	rows := make([]float64, t.H)
+	for y := range rows {
+		rows[y] = 1
+		if ok {
+			lat := p.Top + (float64(y)+0.5)/float64(t.H)*(p.Bottom-p.Top)
+			rows[y] = math.Max(math.Cos(radians(lat)), 1e-9)
+		}
+	}
+	return rows
+}
+
+type boxWeight struct {
+	i int
+	w float64
+}
+
+// boxWeights returns, for each of m destination cells, the source cells
+// among n that it covers and the fraction of each that is covered.
+func boxWeights(n, m int) [][]boxWeight {
+	result := make([][]boxWeight, m)
+	scale := float64(n) / float64(m)
+	for j := range result {
+		lo := float64(j) * scale
+		hi := lo + scale
+		for i := int(lo); i < n && float64(i) < hi; i++ {
+			w := math.Min(hi, float64(i+1)) - math.Max(lo, float64(i))
+			if w > 0 {
+				result[j] = append(result[j], boxWeight{i, w})
+			}
+		}
+	}
+	return result
+}
+
+// FootprintSample samples the texture over a region of the given angular
+// size, in radians, choosing and blending between mipmap levels by the number
+// of pixels the region covers, which for an equirectangular texture grows
+// towards the poles as meridians converge. Without mipmaps, or for a zero
+// footprint, it is equivalent to SphericalSample.
+func (t *Texture) FootprintSample(spherical Vector, footprint float64) float64 {
+	if len(t.mipmaps) == 0 || footprint <= 0 {
+		return t.SphericalSample(spherical)
+	}
+	lod := 0.5 * math.Log2(t.footprintArea(spherical, footprint))
+	if lod <= 0 {
+		return t.SphericalSample(spherical)
+	}
+	if lod >= float64(len(t.mipmaps)) {
+		return t.level(len(t.mipmaps)).SphericalSample(spherical)
+	}
+	i := int(lod)
+	a := t.level(i).SphericalSample(spherical)
+	b := t.level(i + 1).SphericalSample(spherical)
+	return lerp(a, b, lod-float64(i))
+}
+
+// footprintArea returns the area, in pixels, of a square of the given angular
+// size centered on a direction, from the local Jacobian of the projection.
+func (t *Texture) footprintArea(p Vector, footprint float64) float64 {
+	east := Vector{-p.Y, p.X, 0}
+	if east.X == 0 && east.Y == 0 {
+		east = Vector{0, 1, 0}
+	}
+	east = east.Normalize()
+	north := p.Cross(east)
+	u, v := t.project(p)
+	ue, ve := t.project(p.Add(east.MulScalar(footprint)).Normalize())
+	un, vn := t.project(p.Add(north.MulScalar(footprint)).Normalize())
+	due, dun := ue-u, un-u
+	if t.global() {
+		due -= math.Round(due)
+		dun -= math.Round(dun)
+	}
+	w, h := float64(t.W), float64(t.H)
+	return math.Abs(due*w*(vn-v)*h - (ve-v)*h*dun*w)
+}
+
+func (t *Texture) level(i int) *Texture {
+	if i == 0 {
+		return t
+	}
+	m := t.mipmaps[i-1]
+	m.Filter = t.Filter
+	m.Projection = t.Projection
+	return m
+}
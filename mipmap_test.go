@@ -0,0 +1,81 @@
+package demsphere
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+// testStripes returns a global texture of alternating one-pixel columns of 0
+// and 1, which every mipmap level above the first averages to 0.5.
+func testStripes(w, h int) *Texture {
+	t := NewTexture(image.NewGray16(image.Rect(0, 0, w, h)))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			t.Pix[x+y*w] = float64(x % 2)
+		}
+	}
+	t.UpdatePoles()
+	t.BuildMipmaps()
+	return t
+}
+
+func TestFootprintSampleLevels(t *testing.T) {
+	tex := testStripes(256, 128)
+	pixel := 2 * math.Pi / 256
+	// the center of a zero column just south of the equator
+	v := LatLngToVector(90-64.5/128*180, -180+10.5/256*360)
+	tests := []struct {
+		footprint float64
+		want      float64
+	}{
+		{0, 0},
+		{pixel / 2, 0},
+		{math.Sqrt2 * pixel, 0.25},
+		{2 * pixel, 0.5},
+		{64 * pixel, 0.5},
+	}
+	for _, test := range tests {
+		if got := tex.FootprintSample(v, test.footprint); math.Abs(got-test.want) > 1e-3 {
+			t.Errorf("footprint %g pixels: got %g, want %g", test.footprint/pixel, got, test.want)
+		}
+	}
+}
+
+// A footprint of fixed angular size covers more pixels of an equirectangular
+// texture towards the poles, where the meridians converge.
+func TestFootprintAreaGrowsTowardsPoles(t *testing.T) {
+	tex := testStripes(256, 128)
+	footprint := 1e-3
+	equator := tex.footprintArea(LatLngToVector(0, 30), footprint)
+	for _, lat := range []float64{30, 60, -75} {
+		got := tex.footprintArea(LatLngToVector(lat, 30), footprint) / equator
+		want := 1 / math.Cos(radians(lat))
+		if math.Abs(got-want) > 1e-2*want {
+			t.Errorf("latitude %g: area ratio %g, want %g", lat, got, want)
+		}
+	}
+}
+
+// Mipmaps weight rows by their area, so a texture that is 1 only in its
+// polar rows averages to the area of those rows, not to their number.
+func TestBuildMipmapsWeightsRowsByArea(t *testing.T) {
+	tex := NewTexture(image.NewGray16(image.Rect(0, 0, 8, 8)))
+	for x := 0; x < 8; x++ {
+		tex.Pix[x] = 1
+		tex.Pix[x+7*8] = 1
+	}
+	tex.BuildMipmaps()
+	top := tex.mipmaps[len(tex.mipmaps)-1]
+	if top.W != 1 || top.H != 1 {
+		t.Fatalf("top level is %dx%d", top.W, top.H)
+	}
+	var total float64
+	for y := 0; y < 8; y++ {
+		total += math.Cos(radians(90 - (float64(y)+0.5)*180/8))
+	}
+	want := 2 * math.Cos(radians(90-0.5*180/8)) / total
+	if got := top.Pix[0]; math.Abs(got-want) > 1e-9 {
+		t.Errorf("mean %g, want %g", got, want)
+	}
+}
@@ -0,0 +1,342 @@
+package demsphere
+
+import "math"
+
+// FillMethod selects how FillNoData estimates elevations inside holes.
+type FillMethod int
+
+const (
+	// FillNearest copies the value of the nearest valid pixel.
+	FillNearest FillMethod = iota
+	// FillInverseDistance blends the valid pixels bordering each hole,
+	// weighted by inverse squared distance. Each pixel of a hole visits up
+	// to 1024 of its border pixels, so large holes are slow to fill.
+	FillInverseDistance
+	// FillDiffusion solves Laplace's equation over each hole with the
+	// bordering valid pixels as boundary conditions, giving the smoothest
+	// surface.
+	FillDiffusion
+)
+
+// maxFillBorder limits the number of border pixels consulted per hole by
+// FillInverseDistance.
+const maxFillBorder = 1024
+
+// MaskNoData marks pixels equal to any of the given raw 16-bit sample values
+// as invalid, and returns the number of invalid pixels.
+// Negative values are interpreted as signed 16-bit integers, so -32768
+// matches a raw value of 0x8000.
+func (t *Texture) MaskNoData(values ...float64) int {
+	nodata := make(map[float64]bool)
+	for _, v := range values {
+		if v < 0 {
+			v += 0x10000
+		}
+		nodata[v/0xffff] = true
+	}
+	if t.Valid == nil {
+		t.Valid = make([]bool, len(t.Pix))
+		for i := range t.Valid {
+			t.Valid[i] = true
+		}
+	}
+	count := 0
+	for i, p := range t.Pix {
+		if nodata[p] {
+			t.Valid[i] = false
+		}
+		if !t.Valid[i] {
+			count++
+		}
+	}
+	return count
+}
+
+// FillNoData replaces every invalid pixel with an estimate from the valid
+// pixels around it, clears the mask and returns the number of pixels filled.
+// If no pixel is valid there is nothing to fill from, and it returns 0 and
+// leaves the mask in place.
+func (t *Texture) FillNoData(method FillMethod) int {
+	if t.Valid == nil {
+		return 0
+	}
+	holes := t.holes()
+	count := 0
+	for _, h := range holes {
+		count += len(h.pixels)
+	}
+	if count == len(t.Pix) {
+		return 0
+	}
+	if count > 0 {
+		switch method {
+		case FillInverseDistance:
+			for _, h := range holes {
+				t.fillInverseDistance(h)
+			}
+		case FillDiffusion:
+			t.fillDiffusion()
+		default:
+			t.fillNearest()
+		}
+	}
+	t.Valid = nil
+	t.mipmaps = nil
+	t.UpdatePoles()
+	return count
+}
+
+type hole struct {
+	pixels []int
+	border []int
+}
+
+// neighbors returns the 4-connected neighbors of pixel i in a w by h grid,
+// wrapping in x if wrap is set and otherwise stopping at the left and right
+// columns, and always stopping at the top and bottom rows.
+func neighbors(i, w, h int, wrap bool, buf []int) []int {
+	x, y := i%w, i/w
+	buf = buf[:0]
+	if wrap {
+		buf = append(buf, (x+w-1)%w+y*w, (x+1)%w+y*w)
+	} else {
+		if x > 0 {
+			buf = append(buf, i-1)
+		}
+		if x < w-1 {
+			buf = append(buf, i+1)
+		}
+	}
+	if y > 0 {
+		buf = append(buf, i-w)
+	}
+	if y < h-1 {
+		buf = append(buf, i+w)
+	}
+	return buf
+}
+
+// holes returns the 4-connected components of invalid pixels along with the
+// valid pixels that border them.
+func (t *Texture) holes() []hole {
+	var result []hole
+	seen := make([]bool, len(t.Pix))
+	wrap := t.global()
+	var buf []int
+	for start, valid := range t.Valid {
+		if valid || seen[start] {
+			continue
+		}
+		var h hole
+		border := make(map[int]bool)
+		seen[start] = true
+		queue := []int{start}
+		for len(queue) > 0 {
+			i := queue[0]
+			queue = queue[1:]
+			h.pixels = append(h.pixels, i)
+			buf = neighbors(i, t.W, t.H, wrap, buf)
+			for _, j := range buf {
+				if t.Valid[j] {
+					if !border[j] {
+						border[j] = true
+						h.border = append(h.border, j)
+					}
+				} else if !seen[j] {
+					seen[j] = true
+					queue = append(queue, j)
+				}
+			}
+		}
+		result = append(result, h)
+	}
+	return result
+}
+
+// fillNearest assigns every invalid pixel the value of the closest valid
+// pixel, measured in 4-connected steps.
+func (t *Texture) fillNearest() {
+	done := make([]bool, len(t.Pix))
+	var queue []int
+	for i, valid := range t.Valid {
+		if valid {
+			done[i] = true
+			queue = append(queue, i)
+		}
+	}
+	wrap := t.global()
+	var buf []int
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		buf = neighbors(i, t.W, t.H, wrap, buf)
+		for _, j := range buf {
+			if !done[j] {
+				done[j] = true
+				t.Pix[j] = t.Pix[i]
+				queue = append(queue, j)
+			}
+		}
+	}
+}
+
+// fillInverseDistance blends the border of a hole into each of its pixels.
+// Each pixel visits up to maxFillBorder border pixels, so a hole costs its
+// area times the smaller of its perimeter and maxFillBorder.
+func (t *Texture) fillInverseDistance(h hole) {
+	border := h.border
+	if len(border) > maxFillBorder {
+		step := float64(len(border)) / maxFillBorder
+		sampled := make([]int, maxFillBorder)
+		for i := range sampled {
+			sampled[i] = border[int(float64(i)*step)]
+		}
+		border = sampled
+	}
+	wrap := t.global()
+	for _, i := range h.pixels {
+		x, y := i%t.W, i/t.W
+		var sum, total float64
+		for _, j := range border {
+			dx := math.Abs(float64(j%t.W - x))
+			if wrap {
+				dx = math.Min(dx, float64(t.W)-dx)
+			}
+			dy := float64(j/t.W - y)
+			w := 1 / (dx*dx + dy*dy)
+			sum += t.Pix[j] * w
+			total += w
+		}
+		t.Pix[i] = sum / total
+	}
+}
+
+// fillDiffusion solves Laplace's equation over the invalid pixels with the
+// valid pixels as boundary conditions. It uses multigrid V-cycles, which
+// correct the smooth part of the error on successively coarser grids, so that
+// the work grows with the number of invalid pixels rather than faster.
+func (t *Texture) fillDiffusion() {
+	const epsilon = 1e-7
+	const maxCycles = 100
+	g := &laplaceGrid{w: t.W, h: t.H, wrap: t.global(), u: t.Pix, f: make([]float64, len(t.Pix))}
+	for i, valid := range t.Valid {
+		if !valid {
+			g.unknowns = append(g.unknowns, i)
+		}
+	}
+	for cycle := 0; cycle < maxCycles; cycle++ {
+		if g.vcycle() < epsilon {
+			break
+		}
+	}
+}
+
+// laplaceGrid is one level of the multigrid solver for fillDiffusion. The
+// unknown pixels satisfy deg*u[i] - sum(u[neighbors]) = f[i], and the other
+// pixels are fixed. The grid wraps in x if wrap is set.
+type laplaceGrid struct {
+	w, h     int
+	wrap     bool
+	u, f     []float64
+	unknowns []int
+}
+
+func (g *laplaceGrid) neighbors(i int, buf []int) []int {
+	return neighbors(i, g.w, g.h, g.wrap, buf)
+}
+
+// residual returns f[i] - deg*u[i] + sum(u[neighbors]) for the neighbors
+// of pixel i.
+func (g *laplaceGrid) residual(i int, neighbors []int) float64 {
+	r := g.f[i] - float64(len(neighbors))*g.u[i]
+	for _, j := range neighbors {
+		r += g.u[j]
+	}
+	return r
+}
+
+// smooth runs Gauss-Seidel sweeps over the unknowns.
+func (g *laplaceGrid) smooth(sweeps int) {
+	var buf []int
+	for k := 0; k < sweeps; k++ {
+		for _, i := range g.unknowns {
+			buf = g.neighbors(i, buf)
+			sum := g.f[i]
+			for _, j := range buf {
+				sum += g.u[j]
+			}
+			g.u[i] = sum / float64(len(buf))
+		}
+	}
+}
+
+// vcycle improves the unknowns and returns the largest remaining residual,
+// relative to the diagonal.
+func (g *laplaceGrid) vcycle() float64 {
+	const sweeps = 3
+	g.smooth(sweeps)
+	if c := g.coarsen(); c != nil {
+		c.vcycle()
+		for _, i := range g.unknowns {
+			g.u[i] += c.interpolate(i%g.w, i/g.w)
+		}
+		g.smooth(sweeps)
+	} else {
+		g.smooth(10 * sweeps)
+	}
+	var result float64
+	var buf []int
+	for _, i := range g.unknowns {
+		buf = g.neighbors(i, buf)
+		r := g.residual(i, buf)
+		result = math.Max(result, math.Abs(r)/float64(len(buf)))
+	}
+	return result
+}
+
+// interpolate bilinearly interpolates a coarse grid at the center of the
+// fine pixel x, y.
+func (g *laplaceGrid) interpolate(x, y int) float64 {
+	cx, cy := x/2, y/2
+	nx := cx + 2*(x%2) - 1
+	if g.wrap {
+		nx = (nx + g.w) % g.w
+	} else {
+		nx = min(max(nx, 0), g.w-1)
+	}
+	ny := min(max(cy+2*(y%2)-1, 0), g.h-1)
+	return (9*g.u[cx+cy*g.w] + 3*g.u[nx+cy*g.w] + 3*g.u[cx+ny*g.w] + g.u[nx+ny*g.w]) / 16
+}
+
+// coarsen returns the half-resolution error equation for the current
+// residuals, or nil if the grid is too small or has no fixed pixels left.
+// Residuals are summed rather than averaged because the coarse pixels are
+// twice as far apart.
+func (g *laplaceGrid) coarsen() *laplaceGrid {
+	if g.w <= 2 && g.h <= 2 {
+		return nil
+	}
+	w, h := (g.w+1)/2, (g.h+1)/2
+	c := &laplaceGrid{w: w, h: h, wrap: g.wrap, u: make([]float64, w*h), f: make([]float64, w*h)}
+	// only cells wholly inside the holes are unknown, as cells straddling
+	// an edge would move the boundary condition
+	inside := make([]int, w*h)
+	var buf []int
+	for _, i := range g.unknowns {
+		buf = g.neighbors(i, buf)
+		r := g.residual(i, buf)
+		j := i%g.w/2 + i/g.w/2*w
+		c.f[j] += r
+		inside[j]++
+	}
+	for j, n := range inside {
+		x, y := j%w, j/w
+		if n == min(g.w-2*x, 2)*min(g.h-2*y, 2) {
+			c.unknowns = append(c.unknowns, j)
+		}
+	}
+	if len(c.unknowns) == 0 || len(c.unknowns) == len(c.u) {
+		return nil
+	}
+	return c
+}
@@ -0,0 +1,83 @@
+package demsphere
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+var testFillMethods = []FillMethod{FillNearest, FillInverseDistance, FillDiffusion}
+
+func TestMaskNoData(t *testing.T) {
+	tex := NewTexture(image.NewGray16(image.Rect(0, 0, 4, 1)))
+	tex.Pix = []float64{0x8000 / 65535.0, 0.5, 1, 0x8000 / 65535.0}
+	if got := tex.MaskNoData(-32768); got != 2 {
+		t.Errorf("masked %d pixels, want 2", got)
+	}
+	if !tex.Valid[1] || tex.Valid[0] {
+		t.Errorf("mask %v", tex.Valid)
+	}
+	if got := tex.MaskNoData(65535); got != 3 {
+		t.Errorf("masked %d pixels after a second value, want 3", got)
+	}
+}
+
+// testHoleTexture returns a texture that is 0 in its left half and 1 in its
+// right half, with its first column missing. On a global texture that column
+// lies on the antimeridian, between the two halves.
+func testHoleTexture(projection Projection) *Texture {
+	const w, h = 16, 8
+	tex := NewTexture(image.NewGray16(image.Rect(0, 0, w, h)))
+	tex.Projection = projection
+	tex.Valid = make([]bool, w*h)
+	for i := range tex.Pix {
+		x := i % w
+		if x >= w/2 {
+			tex.Pix[i] = 1
+		}
+		tex.Valid[i] = x != 0
+	}
+	return tex
+}
+
+func TestFillNoDataAcrossAntimeridian(t *testing.T) {
+	for _, method := range testFillMethods {
+		tex := testHoleTexture(nil)
+		if n := tex.FillNoData(method); n != tex.H {
+			t.Fatalf("method %d filled %d pixels, want %d", method, n, tex.H)
+		}
+		for y := 0; y < tex.H; y++ {
+			got := tex.Pix[y*tex.W]
+			if method == FillNearest {
+				if got != 0 && got != 1 {
+					t.Errorf("method %d row %d: got %g, want 0 or 1", method, y, got)
+				}
+			} else if math.Abs(got-0.5) > 1e-3 {
+				t.Errorf("method %d row %d: got %g, want 0.5 from both sides", method, y, got)
+			}
+		}
+		if tex.Valid != nil {
+			t.Errorf("method %d left the mask in place", method)
+		}
+	}
+}
+
+func TestFillNoDataRegionalEdge(t *testing.T) {
+	for _, method := range testFillMethods {
+		tex := testHoleTexture(Equirectangular{Left: 0, Right: 40, Top: 60, Bottom: 40})
+		tex.FillNoData(method)
+		for y := 0; y < tex.H; y++ {
+			if got := tex.Pix[y*tex.W]; math.Abs(got) > 1e-6 {
+				t.Errorf("method %d row %d: got %g from the far edge, want 0", method, y, got)
+			}
+		}
+	}
+}
+
+func TestFillNoDataNoValidPixels(t *testing.T) {
+	tex := NewTexture(image.NewGray16(image.Rect(0, 0, 4, 4)))
+	tex.Valid = make([]bool, len(tex.Pix))
+	if n := tex.FillNoData(FillDiffusion); n != 0 || tex.Valid == nil {
+		t.Errorf("filled %d pixels of an empty texture", n)
+	}
+}
@@ -0,0 +1,152 @@
+package demsphere
+
+import "math"
+
+// Projection maps directions on the unit sphere to texture coordinates.
+type Projection interface {
+	// Project returns the texture coordinates of a unit direction. Points
+	// inside the raster have u and v in [0, 1], with u increasing to the
+	// right and v increasing downward.
+	Project(spherical Vector) (u, v float64)
+
+	// Unproject returns the unit direction at texture coordinates u, v.
+	Unproject(u, v float64) Vector
+
+	// Global reports whether the raster is a whole-sphere equirectangular
+	// grid, so that u wraps around and v spans from pole to pole.
+	Global() bool
+}
+
+// Equirectangular is a simple cylindrical projection covering all or part of
+// the sphere. Longitudes are given in the dataset's own convention: they are
+// measured from PrimeMeridian, an east longitude, and increase to the west
+// when WestPositive is set. Left and Right are the longitudes of the raster's
+// left and right edges and Top and Bottom the latitudes of its top and bottom
+// edges, all in degrees.
+type Equirectangular struct {
+	Left, Right   float64
+	Top, Bottom   float64
+	PrimeMeridian float64
+	WestPositive  bool
+}
+
+// GlobalEquirectangular is the default projection of a Texture: longitude
+// -180 at the left edge, east positive, and the north pole at the top.
+var GlobalEquirectangular = Equirectangular{Left: -180, Right: 180, Top: 90, Bottom: -90}
+
+func (p Equirectangular) Project(spherical Vector) (u, v float64) {
+	lat, lng := LatLng(spherical)
+	lng -= p.PrimeMeridian
+	if p.WestPositive {
+		lng = -lng
+	}
+	d := wrapDegrees(lng - (p.Left+p.Right)/2)
+	u = 0.5 + d/(p.Right-p.Left)
+	v = (p.Top - lat) / (p.Top - p.Bottom)
+	return
+}
+
+func (p Equirectangular) Unproject(u, v float64) Vector {
+	lng := p.Left + u*(p.Right-p.Left)
+	if p.WestPositive {
+		lng = -lng
+	}
+	lat := p.Top - v*(p.Top-p.Bottom)
+	return LatLngToVector(lat, lng+p.PrimeMeridian)
+}
+
+func (p Equirectangular) Global() bool {
+	return math.Abs(p.Right-p.Left) == 360 && p.Top == 90 && p.Bottom == -90
+}
+
+// PolarStereographic is a spherical polar stereographic projection. The
+// raster covers projected coordinates MinX..MaxX (left to right) and
+// MinY..MaxY (bottom to top) in the same units as Radius. CentralMeridian is
+// the east longitude pointing from the pole towards the bottom of the raster
+// for a north polar projection, or the top for a south polar one.
+// TrueScaleLatitude is the absolute latitude of true scale, with zero meaning
+// the pole itself.
+type PolarStereographic struct {
+	South             bool
+	Radius            float64
+	CentralMeridian   float64
+	TrueScaleLatitude float64
+	MinX, MaxX        float64
+	MinY, MaxY        float64
+}
+
+func (p PolarStereographic) Project(spherical Vector) (u, v float64) {
+	lat, lng := LatLng(spherical)
+	if p.South {
+		lat = -lat
+	}
+	r := 2 * p.Radius * p.k() * math.Tan(radians(90-lat)/2)
+	a := radians(lng - p.CentralMeridian)
+	x := r * math.Sin(a)
+	y := -r * math.Cos(a)
+	if p.South {
+		y = -y
+	}
+	u = (x - p.MinX) / (p.MaxX - p.MinX)
+	v = (p.MaxY - y) / (p.MaxY - p.MinY)
+	return
+}
+
+func (p PolarStereographic) Unproject(u, v float64) Vector {
+	x := p.MinX + u*(p.MaxX-p.MinX)
+	y := p.MaxY - v*(p.MaxY-p.MinY)
+	if p.South {
+		y = -y
+	}
+	lng := p.CentralMeridian + degrees(math.Atan2(x, -y))
+	lat := 90 - 2*degrees(math.Atan(math.Hypot(x, y)/(2*p.Radius*p.k())))
+	if p.South {
+		lat = -lat
+	}
+	return LatLngToVector(lat, lng)
+}
+
+// k returns the scale factor at the pole.
+func (p PolarStereographic) k() float64 {
+	if p.TrueScaleLatitude == 0 {
+		return 1
+	}
+	return (1 + math.Sin(radians(p.TrueScaleLatitude))) / 2
+}
+
+func (p PolarStereographic) Global() bool {
+	return false
+}
+
+// LatLng returns the latitude and east longitude, in degrees, of a unit
+// direction.
+func LatLng(spherical Vector) (lat, lng float64) {
+	lat = degrees(math.Asin(clamp(spherical.Z, -1, 1)))
+	lng = degrees(math.Atan2(spherical.Y, spherical.X))
+	return
+}
+
+// LatLngToVector returns the unit direction at the given latitude and east
+// longitude, in degrees.
+func LatLngToVector(lat, lng float64) Vector {
+	lat = radians(lat)
+	lng = radians(lng)
+	return Vector{
+		math.Cos(lat) * math.Cos(lng),
+		math.Cos(lat) * math.Sin(lng),
+		math.Sin(lat),
+	}
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+// wrapDegrees wraps an angle into [-180, 180).
+func wrapDegrees(d float64) float64 {
+	return d - 360*math.Floor((d+180)/360)
+}
@@ -0,0 +1,74 @@
+package demsphere
+
+import (
+	"math"
+	"testing"
+)
+
+var testProjections = []Projection{
+	GlobalEquirectangular,
+	Equirectangular{Left: 0, Right: 360, Top: 90, Bottom: -90},
+	Equirectangular{Left: 170, Right: 200, Top: 10, Bottom: -20},
+	Equirectangular{Left: -180, Right: 180, Top: 90, Bottom: -90, PrimeMeridian: 180, WestPositive: true},
+	PolarStereographic{Radius: 1, MinX: -0.5, MaxX: 0.5, MinY: -0.5, MaxY: 0.5},
+	PolarStereographic{South: true, Radius: 1, CentralMeridian: 45, TrueScaleLatitude: 71, MinX: -0.2, MaxX: 0.6, MinY: -0.3, MaxY: 0.4},
+}
+
+func TestProjectionRoundTrip(t *testing.T) {
+	for _, p := range testProjections {
+		for _, u := range []float64{0.01, 0.25, 0.5, 0.8, 0.99} {
+			for _, v := range []float64{0.01, 0.3, 0.5, 0.75, 0.99} {
+				gu, gv := p.Project(p.Unproject(u, v))
+				if math.Abs(gu-u) > 1e-9 || math.Abs(gv-v) > 1e-9 {
+					t.Errorf("%+v: %g, %g round trips to %g, %g", p, u, v, gu, gv)
+				}
+			}
+		}
+	}
+}
+
+func TestEquirectangularConventions(t *testing.T) {
+	tests := []struct {
+		p        Equirectangular
+		lat, lng float64
+		u, v     float64
+	}{
+		{GlobalEquirectangular, 0, 90, 0.75, 0.5},
+		{Equirectangular{Left: 0, Right: 360, Top: 90, Bottom: -90}, 45, -90, 0.75, 0.25},
+		{Equirectangular{Left: -180, Right: 180, Top: 90, Bottom: -90, WestPositive: true}, 0, 90, 0.25, 0.5},
+		{Equirectangular{Left: -180, Right: 180, Top: 90, Bottom: -90, PrimeMeridian: 180}, 0, 180, 0.5, 0.5},
+		{Equirectangular{Left: 170, Right: 200, Top: 10, Bottom: -20}, -5, -175, 0.5, 0.5},
+	}
+	for _, test := range tests {
+		u, v := test.p.Project(LatLngToVector(test.lat, test.lng))
+		if math.Abs(u-test.u) > 1e-9 || math.Abs(v-test.v) > 1e-9 {
+			t.Errorf("%+v at %g, %g: got %g, %g, want %g, %g", test.p, test.lat, test.lng, u, v, test.u, test.v)
+		}
+	}
+}
+
+func TestPolarStereographicConventions(t *testing.T) {
+	p := PolarStereographic{Radius: 1, CentralMeridian: -45, MinX: -1, MaxX: 1, MinY: -1, MaxY: 1}
+	if u, v := p.Project(Vector{0, 0, 1}); math.Abs(u-0.5) > 1e-9 || math.Abs(v-0.5) > 1e-9 {
+		t.Errorf("pole at %g, %g, want the center", u, v)
+	}
+	// the central meridian points from the pole to the bottom of the raster
+	if u, v := p.Project(LatLngToVector(80, -45)); math.Abs(u-0.5) > 1e-9 || v <= 0.5 {
+		t.Errorf("central meridian at %g, %g, want below the pole", u, v)
+	}
+
+	// at the latitude of true scale, a parallel keeps its length
+	for _, south := range []bool{false, true} {
+		const lat = 70
+		p := PolarStereographic{South: south, Radius: 1, TrueScaleLatitude: lat, MinX: -1, MaxX: 1, MinY: -1, MaxY: 1}
+		l := lat
+		if south {
+			l = -lat
+		}
+		u, v := p.Project(LatLngToVector(float64(l), 30))
+		r := math.Hypot(2*u-1, 2*v-1)
+		if want := math.Cos(radians(lat)); math.Abs(r-want) > 1e-9 {
+			t.Errorf("south=%v: parallel %d at radius %g, want %g", south, lat, r, want)
+		}
+	}
+}
@@ -0,0 +1,686 @@
+package demsphere
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// regionDensify is the maximum length, in degrees, of a region boundary
+// segment. Longer edges are subdivided so that edges that are straight in
+// latitude and longitude are followed closely by the great circle arcs used
+// for clipping.
+const regionDensify = 0.5
+
+// maxRegionRadius is the largest angle, in degrees, allowed between a region's
+// center and its boundary.
+const maxRegionRadius = 80
+
+// regionEpsilon is the distance, in gnomonic units (roughly radians), within
+// which a point is considered to lie on a line or on another point. Region
+// boundaries along the equator or the prime meridian coincide with triangle
+// edges and vertices.
+const regionEpsilon = 1e-12
+
+// Region is a part of the sphere bounded by one or more closed rings, which
+// must neither cross nor nest; a point is inside if it is inside any ring.
+// Triangles are judged by the directions at which the DEM is sampled, so on
+// a geodetic ellipsoid the bounds are geodetic latitudes.
+//
+// Geometry is done in a gnomonic projection centered on the region, in which
+// both great circle arcs and triangle edges are straight lines.
+type Region struct {
+	center Vector
+	e1, e2 Vector
+	radius float64
+	rings  [][]regionVertex
+}
+
+type regionVertex struct {
+	p point
+	v Vector
+}
+
+type point struct {
+	X, Y float64
+}
+
+func (a point) sub(b point) point {
+	return point{a.X - b.X, a.Y - b.Y}
+}
+
+func (a point) cross(b point) float64 {
+	return a.X*b.Y - a.Y*b.X
+}
+
+type regionClass int
+
+const (
+	regionOutside regionClass = iota
+	regionInside
+	regionBoundary
+)
+
+// NewRegion returns a region bounded by the given rings, each a list of
+// [longitude, latitude] pairs in degrees. Edges are straight in longitude
+// and latitude, taking the shorter way around in longitude. The region must
+// lie within 80 degrees of its center.
+func NewRegion(rings [][][2]float64) (*Region, error) {
+	var dense [][]Vector
+	var sum Vector
+	for _, ring := range rings {
+		if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+			ring = ring[:len(ring)-1]
+		}
+		if len(ring) < 3 {
+			return nil, errors.New("region ring has fewer than 3 vertices")
+		}
+		var vs []Vector
+		for i, a := range ring {
+			b := ring[(i+1)%len(ring)]
+			if b[0]-a[0] > 180 {
+				b[0] -= 360
+			} else if b[0]-a[0] < -180 {
+				b[0] += 360
+			}
+			n := int(math.Ceil(math.Max(math.Abs(b[0]-a[0]), math.Abs(b[1]-a[1])) / regionDensify))
+			for j := 0; j < max(n, 1); j++ {
+				t := float64(j) / float64(max(n, 1))
+				v := LatLngToVector(lerp(a[1], b[1], t), lerp(a[0], b[0], t))
+				vs = append(vs, v)
+				sum = sum.Add(v)
+			}
+		}
+		dense = append(dense, vs)
+	}
+	if len(dense) == 0 {
+		return nil, errors.New("region has no rings")
+	}
+
+	r := &Region{center: sum.Normalize()}
+	r.e1 = Vector{0, 0, 1}.Cross(r.center)
+	if r.e1.Length() < 1e-9 {
+		r.e1 = Vector{0, 1, 0}
+	}
+	r.e1 = r.e1.Normalize()
+	r.e2 = r.center.Cross(r.e1)
+	for _, vs := range dense {
+		ring := make([]regionVertex, len(vs))
+		var area float64
+		for i, v := range vs {
+			r.radius = math.Max(r.radius, angle(r.center, v))
+			p, _ := r.project(v)
+			ring[i] = regionVertex{p, v}
+		}
+		for i, a := range ring {
+			area += a.p.cross(ring[(i+1)%len(ring)].p)
+		}
+		if area < 0 {
+			for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+				ring[i], ring[j] = ring[j], ring[i]
+			}
+		}
+		r.rings = append(r.rings, ring)
+	}
+	if r.radius > radians(maxRegionRadius) {
+		return nil, fmt.Errorf("region extends %.1f degrees from its center; at most %d are supported", degrees(r.radius), maxRegionRadius)
+	}
+	if err := r.checkDisjoint(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// checkDisjoint returns an error if any two rings cross, touch or nest.
+func (r *Region) checkDisjoint() error {
+	type box struct{ lo, hi point }
+	boxes := make([]box, len(r.rings))
+	for i, ring := range r.rings {
+		b := box{ring[0].p, ring[0].p}
+		for _, v := range ring {
+			b.lo = point{math.Min(b.lo.X, v.p.X), math.Min(b.lo.Y, v.p.Y)}
+			b.hi = point{math.Max(b.hi.X, v.p.X), math.Max(b.hi.Y, v.p.Y)}
+		}
+		boxes[i] = b
+	}
+	for i, a := range r.rings {
+		for j := i + 1; j < len(r.rings); j++ {
+			b := r.rings[j]
+			if boxes[i].hi.X < boxes[j].lo.X || boxes[j].hi.X < boxes[i].lo.X ||
+				boxes[i].hi.Y < boxes[j].lo.Y || boxes[j].hi.Y < boxes[i].lo.Y {
+				continue
+			}
+			if ringContains(a, b[0].p) || ringContains(b, a[0].p) {
+				return fmt.Errorf("region rings %d and %d overlap", i+1, j+1)
+			}
+			for k := range a {
+				p, q := a[k].p, a[(k+1)%len(a)].p
+				for l := range b {
+					if segmentsIntersect(p, q, b[l].p, b[(l+1)%len(b)].p) {
+						return fmt.Errorf("region rings %d and %d overlap", i+1, j+1)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// NewBoundsRegion returns the region between two latitudes and two east
+// longitudes, in degrees. If west is greater than east the region crosses
+// the antimeridian. A region spanning all longitudes must reach a pole.
+func NewBoundsRegion(south, north, west, east float64) (*Region, error) {
+	if south >= north || west == east {
+		return nil, errors.New("region bounds are empty")
+	}
+	if east < west {
+		east += 360
+	}
+	if east-west >= 360 {
+		lat := south
+		if north < 90 {
+			if south > -90 {
+				return nil, errors.New("a region spanning all longitudes must include a pole")
+			}
+			lat = north
+		}
+		return NewRegion([][][2]float64{{
+			{west, lat}, {west + 120, lat}, {west + 240, lat},
+		}})
+	}
+	mid := (west + east) / 2
+	return NewRegion([][][2]float64{{
+		{west, south}, {mid, south}, {east, south},
+		{east, north}, {mid, north}, {west, north},
+	}})
+}
+
+// LoadGeoJSONRegion reads a region from the Polygon and MultiPolygon
+// geometries of a GeoJSON file, which may be a bare geometry, a Feature or a
+// FeatureCollection. Polygons with holes are rejected.
+func LoadGeoJSONRegion(path string) (*Region, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc geoJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var rings [][][2]float64
+	if err := doc.rings(&rings); err != nil {
+		return nil, err
+	}
+	return NewRegion(rings)
+}
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Features    []geoJSON       `json:"features"`
+}
+
+func (g *geoJSON) rings(rings *[][][2]float64) error {
+	switch g.Type {
+	case "FeatureCollection":
+		for i := range g.Features {
+			if err := g.Features[i].rings(rings); err != nil {
+				return err
+			}
+		}
+	case "Feature":
+		if g.Geometry != nil {
+			return g.Geometry.rings(rings)
+		}
+	case "Polygon":
+		var polygon [][][2]float64
+		if err := json.Unmarshal(g.Coordinates, &polygon); err != nil {
+			return err
+		}
+		if err := appendPolygon(rings, polygon); err != nil {
+			return err
+		}
+	case "MultiPolygon":
+		var polygons [][][][2]float64
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return err
+		}
+		for _, polygon := range polygons {
+			if err := appendPolygon(rings, polygon); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported GeoJSON type %q", g.Type)
+	}
+	return nil
+}
+
+// appendPolygon adds the outer ring of a GeoJSON polygon to rings.
+func appendPolygon(rings *[][][2]float64, polygon [][][2]float64) error {
+	if len(polygon) > 1 {
+		return errors.New("GeoJSON polygons with holes are not supported")
+	}
+	if len(polygon) > 0 {
+		*rings = append(*rings, polygon[0])
+	}
+	return nil
+}
+
+// project returns the gnomonic coordinates of a unit vector, and false if it
+// lies in the hemisphere facing away from the region.
+func (r *Region) project(v Vector) (point, bool) {
+	d := v.Dot(r.center)
+	if d <= 1e-6 {
+		return point{}, false
+	}
+	return point{v.Dot(r.e1) / d, v.Dot(r.e2) / d}, true
+}
+
+func (r *Region) unproject(p point) Vector {
+	return r.center.Add(r.e1.MulScalar(p.X)).Add(r.e2.MulScalar(p.Y)).Normalize()
+}
+
+// Contains reports whether a unit vector lies inside the region.
+func (r *Region) Contains(v Vector) bool {
+	p, ok := r.project(v)
+	return ok && r.containsPoint(p)
+}
+
+func (r *Region) containsPoint(p point) bool {
+	for _, ring := range r.rings {
+		if ringContains(ring, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ringContains reports whether a point lies inside a single ring.
+func ringContains(ring []regionVertex, p point) bool {
+	inside := false
+	j := len(ring) - 1
+	for i := range ring {
+		a, b := ring[i].p, ring[j].p
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+		j = i
+	}
+	return inside
+}
+
+// classify reports whether a triangle is entirely outside, entirely inside
+// or straddling the boundary of the region.
+func (r *Region) classify(v1, v2, v3 Vector) regionClass {
+	center := v1.Add(v2).Add(v3).Normalize()
+	radius := math.Max(angle(center, v1), math.Max(angle(center, v2), angle(center, v3)))
+	if angle(center, r.center) > radius+r.radius {
+		return regionOutside
+	}
+	a, ok1 := r.project(v1)
+	b, ok2 := r.project(v2)
+	c, ok3 := r.project(v3)
+	if !ok1 || !ok2 || !ok3 {
+		return regionBoundary
+	}
+	const e = regionEpsilon
+	lo := point{math.Min(a.X, math.Min(b.X, c.X)) - e, math.Min(a.Y, math.Min(b.Y, c.Y)) - e}
+	hi := point{math.Max(a.X, math.Max(b.X, c.X)) + e, math.Max(a.Y, math.Max(b.Y, c.Y)) + e}
+	edges := [3][2]point{{a, b}, {b, c}, {c, a}}
+	for _, ring := range r.rings {
+		for i := range ring {
+			p, q := ring[i].p, ring[(i+1)%len(ring)].p
+			if math.Max(p.X, q.X) < lo.X || math.Min(p.X, q.X) > hi.X ||
+				math.Max(p.Y, q.Y) < lo.Y || math.Min(p.Y, q.Y) > hi.Y {
+				continue
+			}
+			for _, e := range edges {
+				if segmentsIntersect(p, q, e[0], e[1]) {
+					return regionBoundary
+				}
+			}
+		}
+	}
+	if r.containsPoint(point{(a.X + b.X + c.X) / 3, (a.Y + b.Y + c.Y) / 3}) {
+		return regionInside
+	}
+	for _, ring := range r.rings {
+		if pointInTriangle(ring[0].p, a, b, c) {
+			return regionBoundary
+		}
+	}
+	return regionOutside
+}
+
+// clip returns the parts of a counter-clockwise triangle that lie inside
+// the region, triangulated.
+func (r *Region) clip(v1, v2, v3 Vector) [][3]Vector {
+	a, _ := r.project(v1)
+	b, _ := r.project(v2)
+	c, _ := r.project(v3)
+	corners := [3]regionVertex{{a, v1}, {b, v2}, {c, v3}}
+	var result [][3]Vector
+	for _, ring := range r.rings {
+		for _, polygon := range r.clipRing(ring, corners) {
+			result = append(result, earClip(polygon, false)...)
+		}
+	}
+	return result
+}
+
+// clipPiece is a run of a ring inside a triangle, from the point where it
+// enters to the point where it leaves. Positions along the triangle's
+// boundary run from 0 to 3, with corner k at position k.
+type clipPiece struct {
+	vertices  []regionVertex
+	entry     float64
+	exit      float64
+	hasEntry  bool
+	hasExit   bool
+	connected bool
+}
+
+// clipRing returns the intersection of a ring with a convex triangle as a
+// list of polygons (Weiler-Atherton). Each ring segment is clipped against
+// the triangle's edges, and the resulting pieces are joined by walking
+// counter-clockwise along the triangle's boundary from each exit to the
+// next entry. Crossings are always computed from an original ring segment
+// and a triangle edge, so triangles sharing an edge agree on them exactly.
+func (r *Region) clipRing(ring []regionVertex, corners [3]regionVertex) [][]regionVertex {
+	position := func(k int, x point) float64 {
+		e, f := corners[k].p, corners[(k+1)%3].p
+		d := f.sub(e)
+		s := ((x.X-e.X)*d.X + (x.Y-e.Y)*d.Y) / (d.X*d.X + d.Y*d.Y)
+		return float64(k) + clamp(s, 0, 1)
+	}
+
+	// ring vertices on the triangle's corners, such as the points where a
+	// boundary along the equator meets the mesh's vertices, are output as
+	// the corners, so that touching a corner is not mistaken for a crossing.
+	// Crossings are still computed from the original ring, so that triangles
+	// without that corner agree on them.
+	snapped := ring
+	for i, v := range ring {
+		for _, c := range corners {
+			if v.v != c.v && math.Hypot(v.p.X-c.p.X, v.p.Y-c.p.Y) < regionEpsilon {
+				if &snapped[0] == &ring[0] {
+					snapped = append([]regionVertex(nil), ring...)
+				}
+				snapped[i] = c
+			}
+		}
+	}
+	snap := func(x regionVertex, i int) regionVertex {
+		for _, j := range [2]int{i, (i + 1) % len(ring)} {
+			if x.v == ring[j].v {
+				return snapped[j]
+			}
+		}
+		return x
+	}
+
+	var pieces []*clipPiece
+	piece := &clipPiece{}
+	open := false
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		tIn, tOut := 0.0, 1.0
+		in, out := -1, -1
+		outside := false
+		for k := 0; k < 3; k++ {
+			e, f := corners[k].p, corners[(k+1)%3].p
+			dp := side(e, f, p.p)
+			dq := side(e, f, q.p)
+			switch {
+			case dp < 0 && dq < 0:
+				outside = true
+			case dp == 0 && dq == 0:
+				// a segment along an edge is inside only if the region lies
+				// on the triangle's side of it
+				d, g := q.p.sub(p.p), f.sub(e)
+				if d.X*g.X+d.Y*g.Y < 0 {
+					outside = true
+				}
+			case dp < 0:
+				if t := dp / (dp - dq); t > tIn {
+					tIn, in = t, k
+				}
+			case dq < 0:
+				if t := dp / (dp - dq); t < tOut {
+					tOut, out = t, k
+				}
+			}
+		}
+		if outside || tIn > tOut {
+			continue
+		}
+		var xIn, xOut regionVertex
+		if in >= 0 {
+			xIn = snap(r.intersect(p, q, corners[in], corners[(in+1)%3]), i)
+		}
+		if out >= 0 {
+			xOut = snap(r.intersect(p, q, corners[out], corners[(out+1)%3]), i)
+		}
+		if in >= 0 && out >= 0 && xIn.v == xOut.v {
+			// the segment only touches the triangle
+			continue
+		}
+		if in >= 0 {
+			piece = &clipPiece{vertices: []regionVertex{xIn}, entry: position(in, xIn.p), hasEntry: true}
+			pieces = append(pieces, piece)
+			open = true
+		} else if i == 0 {
+			pieces = append(pieces, piece)
+			open = true
+		} else if !open {
+			continue
+		}
+		if out >= 0 {
+			piece.vertices = append(piece.vertices, xOut)
+			piece.exit, piece.hasExit = position(out, xOut.p), true
+			touched := piece.hasEntry
+			for _, v := range piece.vertices {
+				touched = touched && v.v == xOut.v
+			}
+			if touched {
+				// the ring passed through a corner without entering
+				pieces = pieces[:len(pieces)-1]
+			}
+			piece = &clipPiece{}
+			open = false
+		} else {
+			piece.vertices = append(piece.vertices, snapped[(i+1)%len(ring)])
+		}
+	}
+
+	if len(pieces) == 0 || !pieces[0].hasEntry && !pieces[0].hasExit {
+		a, b, c := corners[0].p, corners[1].p, corners[2].p
+		centroid := point{(a.X + b.X + c.X) / 3, (a.Y + b.Y + c.Y) / 3}
+		if len(pieces) > 0 {
+			return [][]regionVertex{pieces[0].vertices}
+		}
+		if ringContains(ring, centroid) && !pointInTriangle(ring[0].p, a, b, c) {
+			return [][]regionVertex{corners[:]}
+		}
+		return nil
+	}
+
+	// the ring started inside the triangle: join the run before the first
+	// entry onto the end of the last piece
+	if first := pieces[0]; !first.hasEntry {
+		if last := pieces[len(pieces)-1]; open {
+			last.vertices = append(last.vertices, first.vertices...)
+			last.exit, last.hasExit = first.exit, first.hasExit
+		}
+		pieces = pieces[1:]
+	}
+	var closed []*clipPiece
+	for _, p := range pieces {
+		if p.hasEntry && p.hasExit {
+			closed = append(closed, p)
+		}
+	}
+	pieces = closed
+
+	var result [][]regionVertex
+	for _, start := range pieces {
+		if start.connected {
+			continue
+		}
+		var polygon []regionVertex
+		for p := start; !p.connected; {
+			p.connected = true
+			polygon = append(polygon, p.vertices...)
+			var next *clipPiece
+			best := math.Inf(1)
+			for _, q := range pieces {
+				d := q.entry - p.exit
+				if d < 0 {
+					d += 3
+				}
+				if d < best && (!q.connected || q == start) {
+					next, best = q, d
+				}
+			}
+			if next == nil {
+				break
+			}
+			for m := math.Floor(p.exit) + 1; m <= p.exit+best; m++ {
+				polygon = append(polygon, corners[int(m)%3])
+			}
+			p = next
+		}
+		var deduped []regionVertex
+		for i, v := range polygon {
+			if v.v != polygon[(i+len(polygon)-1)%len(polygon)].v {
+				deduped = append(deduped, v)
+			}
+		}
+		result = append(result, deduped)
+	}
+	return result
+}
+
+// intersect returns the point where segment pq crosses the line through e
+// and f. Endpoints are put in a canonical order first so that the result
+// does not depend on the direction in which either is traversed.
+func (r *Region) intersect(p, q, e, f regionVertex) regionVertex {
+	if vectorLess(q.v, p.v) {
+		p, q = q, p
+	}
+	if vectorLess(f.v, e.v) {
+		e, f = f, e
+	}
+	d := f.p.sub(e.p)
+	t := d.cross(e.p.sub(p.p)) / d.cross(q.p.sub(p.p))
+	t = clamp(t, 0, 1)
+	if t == 0 {
+		return p
+	}
+	if t == 1 {
+		return q
+	}
+	x := point{p.p.X + t*(q.p.X-p.p.X), p.p.Y + t*(q.p.Y-p.p.Y)}
+	for _, v := range [4]regionVertex{e, f, p, q} {
+		if math.Hypot(x.X-v.p.X, x.Y-v.p.Y) < regionEpsilon {
+			return v
+		}
+	}
+	return regionVertex{x, r.unproject(x)}
+}
+
+func vectorLess(a, b Vector) bool {
+	if a.X != b.X {
+		return a.X < b.X
+	}
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.Z < b.Z
+}
+
+// side returns the signed distance of p to the left of the line from e to f,
+// snapped to zero within regionEpsilon.
+func side(e, f, p point) float64 {
+	d := f.sub(e)
+	s := d.cross(p.sub(e)) / math.Hypot(d.X, d.Y)
+	if math.Abs(s) < regionEpsilon {
+		return 0
+	}
+	return s
+}
+
+func segmentsIntersect(p1, p2, p3, p4 point) bool {
+	d1 := side(p3, p4, p1)
+	d2 := side(p3, p4, p2)
+	d3 := side(p1, p2, p3)
+	d4 := side(p1, p2, p4)
+	return ((d1 > 0) != (d2 > 0) || d1 == 0 || d2 == 0) &&
+		((d3 > 0) != (d4 > 0) || d3 == 0 || d4 == 0)
+}
+
+func pointInTriangle(p, a, b, c point) bool {
+	d1 := b.sub(a).cross(p.sub(a))
+	d2 := c.sub(b).cross(p.sub(b))
+	d3 := a.sub(c).cross(p.sub(c))
+	return d1 >= 0 && d2 >= 0 && d3 >= 0
+}
+
+// earClip triangulates a simple counter-clockwise polygon. Degenerate
+// slivers are dropped unless keep is set, in which case they are emitted so
+// that every edge of the polygon remains part of the result.
+func earClip(polygon []regionVertex, keep bool) [][3]Vector {
+	var result [][3]Vector
+	convex := func(a, b, c regionVertex) bool {
+		return side(a.p, b.p, c.p) > 0 && side(b.p, c.p, a.p) > 0 && side(c.p, a.p, b.p) > 0
+	}
+	vs := append([]regionVertex(nil), polygon...)
+	start := 0
+	for len(vs) > 3 {
+		found := false
+		for n := range vs {
+			i := (start + n) % len(vs)
+			a, b, c := vs[(i+len(vs)-1)%len(vs)], vs[i], vs[(i+1)%len(vs)]
+			if !convex(a, b, c) {
+				continue
+			}
+			ear := true
+			for _, o := range vs {
+				if o.v != a.v && o.v != b.v && o.v != c.v && pointInTriangle(o.p, a.p, b.p, c.p) {
+					ear = false
+					break
+				}
+			}
+			if ear {
+				result = append(result, [3]Vector{a.v, b.v, c.v})
+				vs = append(vs[:i], vs[i+1:]...)
+				start = (i + len(vs) - 1) % len(vs)
+				found = true
+				break
+			}
+		}
+		if !found {
+			// only degenerate ears remain; drop the flattest vertex
+			best, flattest := 0, math.Inf(1)
+			for i := range vs {
+				a, b, c := vs[(i+len(vs)-1)%len(vs)], vs[i], vs[(i+1)%len(vs)]
+				if d := math.Abs(b.p.sub(a.p).cross(c.p.sub(b.p))); d < flattest {
+					best, flattest = i, d
+				}
+			}
+			if keep {
+				a, b, c := vs[(best+len(vs)-1)%len(vs)], vs[best], vs[(best+1)%len(vs)]
+				result = append(result, [3]Vector{a.v, b.v, c.v})
+			}
+			vs = append(vs[:best], vs[best+1:]...)
+		}
+	}
+	if len(vs) == 3 && (keep || convex(vs[0], vs[1], vs[2])) {
+		result = append(result, [3]Vector{vs[0].v, vs[1].v, vs[2].v})
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+package demsphere
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testRegionSource is a smooth globe of hills, so that adaptive refinement
+// varies along region boundaries.
+func testRegionSource() ElevationSource {
+	im := image.NewGray16(image.Rect(0, 0, 360, 180))
+	for y := 0; y < 180; y++ {
+		for x := 0; x < 360; x++ {
+			v := 0.5 + 0.5*math.Sin(float64(x)/7)*math.Sin(float64(y)/5)
+			im.SetGray16(x, y, color.Gray16{Y: uint16(v * 65535)})
+		}
+	}
+	return &TextureSource{NewTexture(im), -5000, 5000}
+}
+
+// Regions whose edges run along the equator or the prime meridian share
+// lines with the seed triangles' edges, where clipping must still leave a
+// clean boundary loop.
+func TestRegionSolidOnSeedEdges(t *testing.T) {
+	source := testRegionSource()
+	tests := []struct {
+		seed                     Seed
+		refinement               Refinement
+		south, north, west, east float64
+	}{
+		{Icosahedron, QuadSubdivision, -30, 30, 0, 90},
+		{Icosahedron, EdgeBisection, -30, 30, 0, 90},
+		{Octahedron, QuadSubdivision, -45, 0, -90, 0},
+		{Octahedron, EdgeBisection, -45, 0, -90, 0},
+		{Octahedron, QuadSubdivision, 0, 45, 0, 45},
+		{Octahedron, EdgeBisection, 0, 45, 0, 45},
+		{CubeSphere, QuadSubdivision, -30, 30, 0, 90},
+		{CubeSphere, EdgeBisection, -30, 30, 0, 90},
+		{Icosahedron, QuadSubdivision, 10, 20, 30, 50},
+	}
+	for _, test := range tests {
+		region, err := NewBoundsRegion(test.south, test.north, test.west, test.east)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, curved := range []bool{true, false} {
+			tri := NewSourceTriangulator(source, 2, 7, 6371000, 100, 10, 1/6371000.0)
+			tri.Seed = test.seed
+			tri.Refinement = test.refinement
+			tri.Region = region
+			surface := tri.Triangulate()
+			solid, err := NewCapSolid(5000/6371000.0, curved).Build(surface)
+			if err != nil {
+				t.Errorf("%+v curved=%v: %v", test, curved, err)
+				continue
+			}
+			if r := Validate(solid); !r.Valid() {
+				t.Errorf("%+v curved=%v: %d boundary, %d non-manifold, %d inconsistent, %d degenerate, %d duplicate",
+					test, curved, r.BoundaryEdges.Count, r.NonManifoldEdges.Count, r.InconsistentEdges.Count,
+					r.Degenerate.Count, r.Duplicate.Count)
+			}
+		}
+	}
+}
+
+func TestBoundsRegionEmpty(t *testing.T) {
+	if _, err := NewBoundsRegion(-10, 10, 20, 20); err == nil {
+		t.Error("expected an error for equal west and east longitudes")
+	}
+}
+
+// Each ring of a multi-ring region must clip only the triangles along its
+// own boundary.
+func TestGeoJSONRegionTwoRings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "region.geojson")
+	doc := `{"type": "MultiPolygon", "coordinates": [
+		[[[10, 10], [30, 10], [30, 25], [10, 25], [10, 10]]],
+		[[[40, -5], [55, -5], [50, 15], [40, -5]]]
+	]}`
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	region, err := LoadGeoJSONRegion(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tri := NewSourceTriangulator(testRegionSource(), 2, 7, 6371000, 100, 10, 1/6371000.0)
+	tri.Region = region
+	solid, err := NewCapSolid(5000/6371000.0, true).Build(tri.Triangulate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := Validate(solid); !r.Valid() {
+		t.Errorf("%d boundary, %d non-manifold, %d inconsistent, %d degenerate, %d duplicate",
+			r.BoundaryEdges.Count, r.NonManifoldEdges.Count, r.InconsistentEdges.Count,
+			r.Degenerate.Count, r.Duplicate.Count)
+	}
+}
+
+func TestGeoJSONRegionRejectsHolesAndOverlaps(t *testing.T) {
+	tests := map[string]string{
+		"hole": `{"type": "Polygon", "coordinates": [
+			[[0, 0], [20, 0], [20, 20], [0, 20], [0, 0]],
+			[[5, 5], [10, 5], [10, 10], [5, 5]]
+		]}`,
+		"crossing": `{"type": "MultiPolygon", "coordinates": [
+			[[[0, 0], [20, 0], [20, 20], [0, 20], [0, 0]]],
+			[[[10, 10], [30, 10], [30, 30], [10, 10]]]
+		]}`,
+		"nested": `{"type": "MultiPolygon", "coordinates": [
+			[[[0, 0], [20, 0], [20, 20], [0, 20], [0, 0]]],
+			[[[5, 5], [10, 5], [10, 10], [5, 5]]]
+		]}`,
+	}
+	for name, doc := range tests {
+		path := filepath.Join(t.TempDir(), "region.geojson")
+		if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadGeoJSONRegion(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// On a geodetic ellipsoid, region bounds are geodetic latitudes, as they are
+// for ToleranceRegions.
+func TestRegionGeodeticBounds(t *testing.T) {
+	region, err := NewBoundsRegion(30, 60, 0, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tri := NewSourceTriangulator(testRegionSource(), 2, 6, 6371000, 100, 10, 1/6371000.0)
+	tri.Ellipsoid = &Ellipsoid{A: 6378137, B: 6378137, C: 5500000, Geodetic: true}
+	tri.Region = region
+	triangles := tri.Triangulate()
+	if len(triangles) == 0 {
+		t.Fatal("empty mesh")
+	}
+	keys := make(map[Vector]Vector)
+	for k, p := range tri.points {
+		keys[p] = k
+	}
+	// Densified boundary segments are great circle arcs, which bulge
+	// poleward of a parallel by up to about 3e-4 degrees.
+	const eps = 1e-3
+	for _, tr := range triangles {
+		for _, p := range [3]Vector{tr.A, tr.B, tr.C} {
+			lat, lng := LatLng(tri.Ellipsoid.direction(keys[p]))
+			if lat < 30-eps || lat > 60+eps || lng < -eps || lng > 40+eps {
+				t.Fatalf("vertex at geodetic %g, %g lies outside the bounds", lat, lng)
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package demsphere
+
+import (
+	"fmt"
+	"math"
+)
+
+// Mercator is a spherical Mercator projection. Left and Right are the east
+// longitudes of the raster's left and right edges and Top and Bottom the
+// latitudes of its top and bottom edges, all in degrees.
+type Mercator struct {
+	Left, Right float64
+	Top, Bottom float64
+}
+
+func (p Mercator) Project(spherical Vector) (u, v float64) {
+	lat, lng := LatLng(spherical)
+	d := wrapDegrees(lng - (p.Left+p.Right)/2)
+	u = 0.5 + d/(p.Right-p.Left)
+	top := mercatorY(p.Top)
+	v = (top - mercatorY(lat)) / (top - mercatorY(p.Bottom))
+	return
+}
+
+func (p Mercator) Unproject(u, v float64) Vector {
+	lng := p.Left + u*(p.Right-p.Left)
+	top := mercatorY(p.Top)
+	y := top - v*(top-mercatorY(p.Bottom))
+	lat := degrees(2*math.Atan(math.Exp(y)) - math.Pi/2)
+	return LatLngToVector(lat, lng)
+}
+
+func (p Mercator) Global() bool {
+	return false
+}
+
+func mercatorY(lat float64) float64 {
+	lat = clamp(lat, -89.9, 89.9)
+	return math.Log(math.Tan(math.Pi/4 + radians(lat)/2))
+}
+
+// NewReliefTriangulator returns a Triangulator that builds a flat
+// raised-relief tile instead of a globe. The tile covers the unit square of
+// the projection, which must be an Equirectangular, Mercator or
+// PolarStereographic projection, laid out in the XY plane with elevation
+// along Z. Cylindrical projections are scaled to be true at the tile's
+// central latitude. Detail level zero is a grid of roughly square cells.
+func NewReliefTriangulator(source ElevationSource, projection Projection, minDetail, maxDetail int, meanRadius, tolerance, exaggeration, scale float64) (*Triangulator, error) {
+	w, h, err := mapSize(projection, meanRadius)
+	if err != nil {
+		return nil, err
+	}
+	tri := NewSourceTriangulator(source, minDetail, maxDetail, meanRadius, tolerance, exaggeration, scale)
+	tri.domain = &plane{projection, meanRadius, w, h}
+	return tri, nil
+}
+
+// mapSize returns the width and height, in meters, of a map of the unit
+// square of a projection.
+func mapSize(projection Projection, radius float64) (w, h float64, err error) {
+	switch p := projection.(type) {
+	case Equirectangular:
+		k := radius * math.Cos(radians((p.Top+p.Bottom)/2))
+		return k * radians(math.Abs(p.Right-p.Left)), radius * radians(math.Abs(p.Top-p.Bottom)), nil
+	case Mercator:
+		k := radius * math.Cos(radians((p.Top+p.Bottom)/2))
+		return k * radians(math.Abs(p.Right-p.Left)), k * math.Abs(mercatorY(p.Top)-mercatorY(p.Bottom)), nil
+	case PolarStereographic:
+		k := radius / p.Radius
+		return k * (p.MaxX - p.MinX), k * (p.MaxY - p.MinY), nil
+	}
+	return 0, 0, fmt.Errorf("unsupported relief projection %T", projection)
+}
+
+// plane is the domain of a relief tile, keyed by texture coordinates u, v.
+type plane struct {
+	projection Projection
+	radius     float64
+	w, h       float64
+}
+
+func (p *plane) cells() (nx, ny int) {
+	if p.w > p.h {
+		return max(1, int(math.Round(p.w/p.h))), 1
+	}
+	return 1, max(1, int(math.Round(p.h/p.w)))
+}
+
+func (p *plane) seed() []Triangle {
+	nx, ny := p.cells()
+	var triangles []Triangle
+	for j := 0; j < ny; j++ {
+		v0 := float64(j) / float64(ny)
+		v1 := float64(j+1) / float64(ny)
+		for i := 0; i < nx; i++ {
+			u0 := float64(i) / float64(nx)
+			u1 := float64(i+1) / float64(nx)
+			a := Vector{u0, v1, 0}
+			b := Vector{u1, v1, 0}
+			c := Vector{u1, v0, 0}
+			d := Vector{u0, v0, 0}
+			triangles = append(triangles, Triangle{a, b, c}, Triangle{a, c, d})
+		}
+	}
+	return triangles
+}
+
+func (p *plane) quads() [][4]Vector {
+	return nil
+}
+
+func (p *plane) midpoint(a, b Vector) Vector {
+	return Vector{(a.X + b.X) / 2, (a.Y + b.Y) / 2, 0}
+}
+
+func (p *plane) sampleMidpoint(a, b Vector) Vector {
+	return p.midpoint(a, b)
+}
+
+func (p *plane) forgetSamples() {}
+
+func (p *plane) reset() {}
+
+func (p *plane) direction(v Vector) Vector {
+	return p.projection.Unproject(v.X, v.Y)
+}
+
+func (p *plane) point(v Vector, elevation float64) Vector {
+	return Vector{v.X * p.w, (1 - v.Y) * p.h, elevation}
+}
+
+func (p *plane) footprint(detail int) float64 {
+	nx, ny := p.cells()
+	size := math.Max(p.w/float64(nx), p.h/float64(ny))
+	return math.Ldexp(size/p.radius, -detail)
+}
@@ -0,0 +1,64 @@
+package demsphere
+
+import "math"
+
+// maxRecommendedDetail bounds the search in RecommendedMaxDetail.
+const maxRecommendedDetail = 30
+
+// Resolution returns the angular size, in radians, of a pixel at the center
+// of the raster, taking the finer of its width and height.
+func (t *Texture) Resolution() float64 {
+	c := t.unproject(0.5, 0.5)
+	dx := angle(c, t.unproject(0.5+1/float64(t.W), 0.5))
+	dy := angle(c, t.unproject(0.5, 0.5+1/float64(t.H)))
+	return math.Min(dx, dy)
+}
+
+// SourceResolution returns the angular resolution, in radians, of a source's
+// base layer: the pixel size of its texture, or half the shortest
+// wavelength of a spherical harmonic expansion. Regional inserts and polar
+// caps are ignored, as they carry their own maximum detail. It returns 0 if
+// the resolution is unknown.
+func SourceResolution(source ElevationSource) float64 {
+	switch s := source.(type) {
+	case *TextureSource:
+		return s.Texture.Resolution()
+	case *Inverted:
+		return SourceResolution(s.Source)
+	case *Layers:
+		return SourceResolution(s.Base)
+	case *PolarCaps:
+		return SourceResolution(s.Base)
+	case *SphericalHarmonics:
+		degree := s.degree
+		if s.Degree > 0 && s.Degree < degree {
+			degree = s.Degree
+		}
+		return math.Pi / float64(max(degree, 1))
+	}
+	return 0
+}
+
+// Footprint returns the approximate angular size, in radians, of the
+// Triangulator's triangles at a detail level.
+func (tri *Triangulator) Footprint(detail int) float64 {
+	tri.configure()
+	return tri.domain.footprint(detail)
+}
+
+// RecommendedMaxDetail returns the shallowest detail level whose triangle
+// edges are no longer than a pixel of the source. Vertices that close
+// together already sample the DEM at its Nyquist limit, so refining further
+// only adds triangles. It returns -1 if the source's resolution is unknown.
+func (tri *Triangulator) RecommendedMaxDetail() int {
+	resolution := SourceResolution(tri.source)
+	if resolution <= 0 {
+		return -1
+	}
+	for detail := 0; detail < maxRecommendedDetail; detail++ {
+		if tri.Footprint(detail) <= resolution {
+			return detail
+		}
+	}
+	return maxRecommendedDetail
+}
@@ -0,0 +1,75 @@
+package demsphere
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+// testFlatSource is a source of unknown resolution.
+type testFlatSource struct{}
+
+func (testFlatSource) Elevation(spherical Vector, footprint float64) float64 {
+	return 0
+}
+
+func testTexture(w, h int) *Texture {
+	return NewTexture(image.NewGray16(image.Rect(0, 0, w, h)))
+}
+
+func TestSourceResolution(t *testing.T) {
+	texture := &TextureSource{testTexture(360, 180), 0, 1}
+	degree := radians(1)
+	harmonics := NewSphericalHarmonics(make([][]float64, 91), nil)
+	truncated := NewSphericalHarmonics(make([][]float64, 91), nil)
+	truncated.Degree = 30
+	tests := []struct {
+		source ElevationSource
+		want   float64
+	}{
+		{texture, degree},
+		{&TextureSource{testTexture(720, 180), 0, 1}, degree / 2},
+		{&Inverted{Source: texture}, degree},
+		{&Layers{Base: texture}, degree},
+		{&PolarCaps{Base: texture}, degree},
+		{harmonics, math.Pi / 90},
+		{truncated, math.Pi / 30},
+		{testFlatSource{}, 0},
+	}
+	for _, test := range tests {
+		if got := SourceResolution(test.source); math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("%T: got %g, want %g", test.source, got, test.want)
+		}
+	}
+}
+
+// The recommended level is the first whose footprint is within a pixel.
+func TestRecommendedMaxDetail(t *testing.T) {
+	for _, seed := range []Seed{Icosahedron, Octahedron, CubeSphere} {
+		for _, w := range []int{90, 360, 1440, 8000} {
+			source := &TextureSource{testTexture(w, w/2), 0, 1}
+			tri := NewSourceTriangulator(source, 0, 0, 1, 1, 1, 1)
+			tri.Seed = seed
+			resolution := SourceResolution(source)
+			// a seed edge halves with each level
+			want := int(math.Ceil(math.Log2(seed.edge() / resolution)))
+			got := tri.RecommendedMaxDetail()
+			if got != want {
+				t.Errorf("seed %d, width %d: got %d, want %d", seed, w, got, want)
+			}
+			if tri.Footprint(got) > resolution || tri.Footprint(got-1) <= resolution {
+				t.Errorf("seed %d, width %d: level %d is not the first within a pixel", seed, w, got)
+			}
+		}
+	}
+	// a one degree DEM needs icosahedron edges of 63.4 degrees to be halved
+	// six times
+	tri := NewSourceTriangulator(&TextureSource{testTexture(360, 180), 0, 1}, 0, 0, 1, 1, 1, 1)
+	if got := tri.RecommendedMaxDetail(); got != 6 {
+		t.Errorf("one degree DEM: got %d, want 6", got)
+	}
+	tri = NewSourceTriangulator(testFlatSource{}, 0, 0, 1, 1, 1, 1)
+	if got := tri.RecommendedMaxDetail(); got != -1 {
+		t.Errorf("unknown resolution: got %d, want -1", got)
+	}
+}
@@ -1,5 +1,54 @@
 package demsphere
 
+import "math"
+
+// Seed selects the polyhedron whose faces are subdivided to triangulate the
+// sphere. The icosahedron gives the most uniform triangles. The octahedron
+// has edges along the equator and the prime and 90 degree meridians, which
+// is convenient for splitting the globe into hemispheres. The cube-sphere
+// refines each face of a cube as quads, each split into four on the same
+// face, evenly in angle as in an equiangular cube map, and leaves each
+// finished quad as two triangles. Its vertices line up with the faces of a
+// cube map at every level. EdgeBisection refines its faces as triangles,
+// like those of the other seeds.
+type Seed int
+
+const (
+	Icosahedron Seed = iota
+	Octahedron
+	CubeSphere
+)
+
+// Triangles returns the seed's faces, projected onto the unit sphere.
+func (s Seed) Triangles() []Triangle {
+	switch s {
+	case Octahedron:
+		return NewOctahedron()
+	case CubeSphere:
+		return NewCubeSphere()
+	default:
+		return NewIcosahedron()
+	}
+}
+
+// icosahedronEdge is the angle, in radians, subtended by an edge of the
+// unit icosahedron.
+const icosahedronEdge = 1.1071487177940904
+
+// edge returns the largest angle, in radians, subtended by an edge of the
+// seed's faces.
+func (s Seed) edge() float64 {
+	switch s {
+	case Octahedron:
+		return math.Pi / 2
+	case CubeSphere:
+		// the diagonals of the cube's faces
+		return math.Acos(-1.0 / 3)
+	default:
+		return icosahedronEdge
+	}
+}
+
 func NewIcosahedron() []Triangle {
 	const a = 0.8506507174597755
 	const b = 0.5257312591858783
@@ -48,3 +97,53 @@ func NewIcosahedron() []Triangle {
 	}
 	return triangles
 }
+
+func NewOctahedron() []Triangle {
+	var triangles []Triangle
+	for _, sx := range []float64{1, -1} {
+		for _, sy := range []float64{1, -1} {
+			for _, sz := range []float64{1, -1} {
+				a := Vector{sx, 0, 0}
+				b := Vector{0, sy, 0}
+				c := Vector{0, 0, sz}
+				if sx*sy*sz < 0 {
+					b, c = c, b
+				}
+				triangles = append(triangles, Triangle{a, b, c})
+			}
+		}
+	}
+	return triangles
+}
+
+// NewCubeSphere returns the faces of a cube projected onto the unit sphere,
+// each split into two triangles along a diagonal.
+func NewCubeSphere() []Triangle {
+	var triangles []Triangle
+	for _, q := range cubeFaces() {
+		p0, p1, p2, p3 := q[0].Normalize(), q[1].Normalize(), q[2].Normalize(), q[3].Normalize()
+		triangles = append(triangles, Triangle{p0, p1, p2}, Triangle{p0, p2, p3})
+	}
+	return triangles
+}
+
+// cubeFaces returns the faces of the cube [-1, 1]³, counterclockwise seen
+// from outside.
+func cubeFaces() [][4]Vector {
+	axes := []Vector{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	var faces [][4]Vector
+	for i, n := range axes {
+		for _, s := range []float64{1, -1} {
+			n := n.MulScalar(s)
+			u := axes[(i+1)%3]
+			v := axes[(i+2)%3]
+			if s < 0 {
+				u, v = v, u
+			}
+			faces = append(faces, [4]Vector{
+				n.Sub(u).Sub(v), n.Add(u).Sub(v), n.Add(u).Add(v), n.Sub(u).Add(v),
+			})
+		}
+	}
+	return faces
+}
@@ -0,0 +1,145 @@
+package demsphere
+
+// ElevationSource provides elevations, in meters above the reference
+// radius, for directions on the unit sphere.
+type ElevationSource interface {
+	// Elevation returns the elevation at a unit direction, averaged over a
+	// region of the given angular size in radians. A zero footprint asks
+	// for full detail.
+	Elevation(spherical Vector, footprint float64) float64
+}
+
+// TextureSource maps the normalized values of a Texture linearly onto
+// MinElevation..MaxElevation.
+type TextureSource struct {
+	Texture      *Texture
+	MinElevation float64
+	MaxElevation float64
+}
+
+func (s *TextureSource) Elevation(spherical Vector, footprint float64) float64 {
+	d := s.Texture.FootprintSample(spherical, footprint)
+	return s.MinElevation + d*(s.MaxElevation-s.MinElevation)
+}
+
+// Inverted mirrors a source within MinElevation..MaxElevation, so that the
+// highest points become the lowest.
+type Inverted struct {
+	Source       ElevationSource
+	MinElevation float64
+	MaxElevation float64
+}
+
+func (s *Inverted) Elevation(spherical Vector, footprint float64) float64 {
+	return s.MinElevation + s.MaxElevation - s.Source.Elevation(spherical, footprint)
+}
+
+func (s *Inverted) MaxDetail(v1, v2, v3 Vector) int {
+	if d, ok := s.Source.(DetailSource); ok {
+		return d.MaxDetail(v1, v2, v3)
+	}
+	return 0
+}
+
+// PolarCaps combines a global base source with higher resolution north and
+// south polar sources, either of which may be nil. A cap is used poleward of
+// Latitude and blended smoothly into the base over a band Feather degrees
+// wide on the equatorward side of Latitude. Where a cap's raster does not
+// reach that far, it also blends into the base over a band EdgeFeather
+// pixels wide inside the raster's edge, as an Insert does.
+type PolarCaps struct {
+	Base        ElevationSource
+	North       ElevationSource
+	South       ElevationSource
+	Latitude    float64
+	Feather     float64
+	EdgeFeather float64
+}
+
+func (s *PolarCaps) Elevation(spherical Vector, footprint float64) float64 {
+	lat, _ := LatLng(spherical)
+	polar := s.North
+	if lat < 0 {
+		lat = -lat
+		polar = s.South
+	}
+	if polar == nil {
+		return s.Base.Elevation(spherical, footprint)
+	}
+	w := 1.0
+	if s.Feather > 0 {
+		w = smoothstep(clamp((lat-s.Latitude+s.Feather)/s.Feather, 0, 1))
+	} else if lat < s.Latitude {
+		w = 0
+	}
+	if w > 0 {
+		if t := coverageTexture(polar); t != nil {
+			w *= edgeWeight(t, spherical, s.EdgeFeather)
+		}
+	}
+	switch w {
+	case 0:
+		return s.Base.Elevation(spherical, footprint)
+	case 1:
+		return polar.Elevation(spherical, footprint)
+	}
+	a := s.Base.Elevation(spherical, footprint)
+	b := polar.Elevation(spherical, footprint)
+	return lerp(a, b, w)
+}
+
+// coverageTexture returns the texture that bounds the region a source
+// covers, or nil if it covers the whole sphere.
+func coverageTexture(source ElevationSource) *Texture {
+	switch s := source.(type) {
+	case *TextureSource:
+		return s.Texture
+	case *Inverted:
+		return coverageTexture(s.Source)
+	case *Layers:
+		return coverageTexture(s.Base)
+	}
+	return nil
+}
+
+// edgeWeight returns 0 outside a texture, rising smoothly to 1 over the band
+// feather pixels wide inside its edge.
+func edgeWeight(t *Texture, spherical Vector, feather float64) float64 {
+	d := t.EdgeDistance(spherical)
+	if d <= 0 {
+		return 0
+	}
+	if feather <= 0 {
+		return 1
+	}
+	return smoothstep(clamp(d/feather, 0, 1))
+}
+
+// sourceTextures returns the textures that a source reads from.
+func sourceTextures(source ElevationSource) []*Texture {
+	switch s := source.(type) {
+	case *TextureSource:
+		return []*Texture{s.Texture}
+	case *Inverted:
+		return sourceTextures(s.Source)
+	case *Layers:
+		result := sourceTextures(s.Base)
+		for _, in := range s.Inserts {
+			result = append(result, in.Source.Texture)
+		}
+		return result
+	case *PolarCaps:
+		var result []*Texture
+		for _, c := range []ElevationSource{s.Base, s.North, s.South} {
+			if c != nil {
+				result = append(result, sourceTextures(c)...)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func smoothstep(x float64) float64 {
+	return x * x * (3 - 2*x)
+}
@@ -0,0 +1,37 @@
+package demsphere
+
+import "math"
+
+// TextureStats summarizes the valid pixels of a texture, whose values range
+// from 0 to 1.
+type TextureStats struct {
+	Min, Max  float64
+	Mean      float64
+	Histogram []int // pixel counts in equal bins from 0 to 1
+	NoData    int   // number of invalid pixels
+}
+
+// Stats computes the statistics of the texture's valid pixels, with a
+// histogram of the given number of bins.
+func (t *Texture) Stats(bins int) TextureStats {
+	s := TextureStats{Min: math.Inf(1), Max: math.Inf(-1), Histogram: make([]int, bins)}
+	var sum float64
+	for i, p := range t.Pix {
+		if t.Valid != nil && !t.Valid[i] {
+			s.NoData++
+			continue
+		}
+		s.Min = math.Min(s.Min, p)
+		s.Max = math.Max(s.Max, p)
+		sum += p
+		if bins > 0 {
+			s.Histogram[min(int(p*float64(bins)), bins-1)]++
+		}
+	}
+	if n := len(t.Pix) - s.NoData; n > 0 {
+		s.Mean = sum / float64(n)
+	} else {
+		s.Min, s.Max = 0, 0
+	}
+	return s
+}
@@ -0,0 +1,34 @@
+package demsphere
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTextureStats(t *testing.T) {
+	texture := &Texture{W: 3, H: 2, Pix: []float64{0, 0.25, 0.5, 1, 0.9, 0.3}}
+	texture.Valid = []bool{true, true, true, true, false, true}
+	s := texture.Stats(4)
+	if s.Min != 0 || s.Max != 1 || s.NoData != 1 {
+		t.Errorf("got min %g, max %g, %d no data", s.Min, s.Max, s.NoData)
+	}
+	if want := (0 + 0.25 + 0.5 + 1 + 0.3) / 5; s.Mean != want {
+		t.Errorf("got mean %g, want %g", s.Mean, want)
+	}
+	// bins are [0, 0.25), [0.25, 0.5), [0.5, 0.75) and [0.75, 1]
+	if want := []int{1, 2, 1, 1}; !slices.Equal(s.Histogram, want) {
+		t.Errorf("got histogram %v, want %v", s.Histogram, want)
+	}
+
+	if s := texture.Stats(0); len(s.Histogram) != 0 || s.Max != 1 {
+		t.Errorf("no bins: got %+v", s)
+	}
+}
+
+func TestTextureStatsAllNoData(t *testing.T) {
+	texture := &Texture{W: 2, H: 1, Pix: []float64{0.5, 0.7}, Valid: []bool{false, false}}
+	s := texture.Stats(2)
+	if s.Min != 0 || s.Max != 0 || s.Mean != 0 || s.NoData != 2 || !slices.Equal(s.Histogram, []int{0, 0}) {
+		t.Errorf("got %+v", s)
+	}
+}
@@ -6,10 +6,40 @@ import (
 	"math"
 )
 
+// Filter selects the reconstruction filter used to sample a Texture.
+// Bilinear is the fastest. Bicubic (Catmull-Rom) reads a 4x4 neighborhood
+// and costs 4-6x as much per sample, giving smooth slopes when the mesh is
+// finer than the DEM. Lanczos3 reads a 6x6 neighborhood, costs 13-20x as
+// much per sample as Bilinear and preserves the most detail, at the cost of
+// slight ringing around sharp features.
+type Filter int
+
+const (
+	Bilinear Filter = iota
+	Bicubic
+	Lanczos3
+)
+
 type Texture struct {
 	W   int
 	H   int
 	Pix []float64
+
+	// Filter is the reconstruction filter used by Sample and by the
+	// Triangulators that read the texture.
+	Filter Filter
+
+	// Projection maps directions to texture coordinates. A nil Projection
+	// means GlobalEquirectangular.
+	Projection Projection
+
+	// Valid marks which pixels hold real data. A nil mask means every pixel
+	// is valid. See MaskNoData and FillNoData.
+	Valid []bool
+
+	north   float64
+	south   float64
+	mipmaps []*Texture
 }
 
 func NewTexture(im image.Image) *Texture {
@@ -17,46 +47,252 @@ func NewTexture(im image.Image) *Texture {
 	w := gray.Bounds().Size().X
 	h := gray.Bounds().Size().Y
 	data := gray16ToFloat64s(gray)
-	return &Texture{w, h, data}
+	t := &Texture{W: w, H: h, Pix: data}
+	t.UpdatePoles()
+	return t
 }
 
-func (t *Texture) BilinearSample(u, v float64) float64 {
-	u -= math.Floor(u)
-	v -= math.Floor(v)
+// UpdatePoles recomputes the pole values as the mean of the first and last
+// rows. It must be called after Pix is modified.
+func (t *Texture) UpdatePoles() {
+	t.north = t.rowMean(0)
+	t.south = t.rowMean(t.H - 1)
+}
+
+// Invert returns a copy of the texture with every value v replaced by 1-v.
+func (t *Texture) Invert() *Texture {
+	pix := make([]float64, len(t.Pix))
+	for i, p := range t.Pix {
+		pix[i] = 1 - p
+	}
+	var valid []bool
+	if t.Valid != nil {
+		valid = append(valid, t.Valid...)
+	}
+	inverted := &Texture{W: t.W, H: t.H, Pix: pix, Filter: t.Filter, Projection: t.Projection, Valid: valid}
+	inverted.UpdatePoles()
+	return inverted
+}
+
+func (t *Texture) rowMean(y int) float64 {
+	var sum float64
+	for _, p := range t.Pix[y*t.W : (y+1)*t.W] {
+		sum += p
+	}
+	return sum / float64(t.W)
+}
+
+func (t *Texture) global() bool {
+	return t.Projection == nil || t.Projection.Global()
+}
+
+func (t *Texture) project(spherical Vector) (u, v float64) {
+	if t.Projection == nil {
+		return GlobalEquirectangular.Project(spherical)
+	}
+	return t.Projection.Project(spherical)
+}
+
+// Covers reports whether the given direction lies within the raster.
+func (t *Texture) Covers(spherical Vector) bool {
+	if t.global() {
+		return true
+	}
+	u, v := t.project(spherical)
+	return u >= 0 && u <= 1 && v >= 0 && v <= 1
+}
+
+func (t *Texture) unproject(u, v float64) Vector {
+	if t.Projection == nil {
+		return GlobalEquirectangular.Unproject(u, v)
+	}
+	return t.Projection.Unproject(u, v)
+}
+
+// EdgeDistance returns how far inside the raster a direction lies, in
+// pixels from the nearest edge. It is negative outside the raster and
+// infinite for global textures.
+func (t *Texture) EdgeDistance(spherical Vector) float64 {
+	if t.global() {
+		return math.Inf(1)
+	}
+	u, v := t.project(spherical)
 	x := u * float64(t.W)
 	y := v * float64(t.H)
-	x0 := int(x)
-	y0 := int(y)
-	x1 := x0 + 1
-	y1 := y0 + 1
+	return math.Min(math.Min(x, float64(t.W)-x), math.Min(y, float64(t.H)-y))
+}
+
+// Bounds returns the center of the raster and the angle, in radians, from
+// the center to the farthest point sampled along its edges.
+func (t *Texture) Bounds() (center Vector, radius float64) {
+	center = t.unproject(0.5, 0.5)
+	if t.global() {
+		return center, math.Pi
+	}
+	const n = 8
+	for i := 0; i <= n; i++ {
+		f := float64(i) / n
+		for _, uv := range [][2]float64{{f, 0}, {f, 1}, {0, f}, {1, f}} {
+			p := t.unproject(uv[0], uv[1])
+			radius = math.Max(radius, angle(center, p))
+		}
+	}
+	return center, radius
+}
+
+func (t *Texture) wrapX(x int) int {
+	x %= t.W
+	if x < 0 {
+		x += t.W
+	}
+	return x
+}
+
+// indexX wraps x around a global texture and clamps it otherwise.
+func (t *Texture) indexX(x int) int {
+	if t.global() {
+		return t.wrapX(x)
+	}
+	if x < 0 {
+		return 0
+	}
+	if x >= t.W {
+		return t.W - 1
+	}
+	return x
+}
+
+func (t *Texture) clampY(y int) int {
+	if y < 0 {
+		return 0
+	}
+	if y >= t.H {
+		return t.H - 1
+	}
+	return y
+}
+
+// BilinearSample samples the texture at u, v, where pixel centers lie at
+// (x+0.5)/W, (y+0.5)/H. On a global texture, longitude (u) wraps around and
+// latitude (v) is clamped, blending towards the mean of the polar row within
+// half a pixel of v=0 and v=1 so that the poles are single points. Other
+// textures are clamped at their edges.
+func (t *Texture) BilinearSample(u, v float64) float64 {
+	global := t.global()
+	if global {
+		u -= math.Floor(u)
+	}
+	x := u*float64(t.W) - 0.5
+	y := v*float64(t.H) - 0.5
+	x0 := int(math.Floor(x))
 	x -= float64(x0)
+	x1 := t.indexX(x0 + 1)
+	x0 = t.indexX(x0)
+	if global && y < 0 {
+		d := t.rowSample(0, x0, x1, x)
+		return lerp(t.north, d, clamp(2*y+1, 0, 1))
+	}
+	if global && y > float64(t.H-1) {
+		d := t.rowSample(t.H-1, x0, x1, x)
+		return lerp(d, t.south, clamp(2*(y-float64(t.H-1)), 0, 1))
+	}
+	y0 := int(math.Floor(y))
 	y -= float64(y0)
-	if x0 >= t.W {
-		x0 = 0
+	y1 := t.clampY(y0 + 1)
+	y0 = t.clampY(y0)
+	d0 := t.rowSample(y0, x0, x1, x)
+	d1 := t.rowSample(y1, x0, x1, x)
+	return lerp(d0, d1, y)
+}
+
+// BicubicSample samples the texture at u, v with a Catmull-Rom kernel,
+// following the same conventions as BilinearSample.
+func (t *Texture) BicubicSample(u, v float64) float64 {
+	return t.kernelSample(u, v, 2, catmullRom)
+}
+
+// LanczosSample samples the texture at u, v with a Lanczos-3 kernel,
+// following the same conventions as BilinearSample.
+func (t *Texture) LanczosSample(u, v float64) float64 {
+	return t.kernelSample(u, v, 3, lanczos3)
+}
+
+// Sample samples the texture at u, v using the texture's Filter.
+func (t *Texture) Sample(u, v float64) float64 {
+	switch t.Filter {
+	case Bicubic:
+		return t.BicubicSample(u, v)
+	case Lanczos3:
+		return t.LanczosSample(u, v)
+	default:
+		return t.BilinearSample(u, v)
 	}
-	if y0 >= t.H {
-		y0 = 0
+}
+
+// kernelSample evaluates a separable kernel of the given radius around u, v.
+// On a global texture, rows beyond a pole are read from the opposite
+// meridian, which is where they lie on the sphere.
+func (t *Texture) kernelSample(u, v float64, radius int, kernel func(float64) float64) float64 {
+	global := t.global()
+	if global {
+		u -= math.Floor(u)
 	}
-	if x1 >= t.W {
-		x1 = 0
+	x := u*float64(t.W) - 0.5
+	y := v*float64(t.H) - 0.5
+	var pole, f float64
+	if global && y < 0 {
+		pole, f = t.north, clamp(2*y+1, 0, 1)
+		y = 0
+	} else if global && y > float64(t.H-1) {
+		pole, f = t.south, clamp(2*(float64(t.H-1)-y)+1, 0, 1)
+		y = float64(t.H - 1)
+	} else {
+		f = 1
 	}
-	if y1 >= t.H {
-		y1 = 0
+	x0 := int(math.Floor(x))
+	y0 := int(math.Floor(y))
+	var wx [6]float64
+	for i := range 2 * radius {
+		wx[i] = kernel(x - float64(x0-radius+1+i))
 	}
-	var d float64
-	d += t.Pix[x0+y0*t.W] * ((1 - x) * (1 - y))
-	d += t.Pix[x0+y1*t.W] * ((1 - x) * y)
-	d += t.Pix[x1+y0*t.W] * (x * (1 - y))
-	d += t.Pix[x1+y1*t.W] * (x * y)
-	return d
+	var sum, total float64
+	for j := range 2 * radius {
+		py := y0 - radius + 1 + j
+		wy := kernel(y - float64(py))
+		if wy == 0 {
+			continue
+		}
+		for i := range 2 * radius {
+			w := wx[i] * wy
+			sum += w * t.pixel(x0-radius+1+i, py)
+			total += w
+		}
+	}
+	return lerp(pole, sum/total, f)
+}
+
+func (t *Texture) pixel(x, y int) float64 {
+	if !t.global() {
+		return t.Pix[t.indexX(x)+t.clampY(y)*t.W]
+	}
+	if y < 0 {
+		x += t.W / 2
+		y = -y - 1
+	} else if y >= t.H {
+		x += t.W / 2
+		y = 2*t.H - y - 1
+	}
+	return t.Pix[t.wrapX(x)+t.clampY(y)*t.W]
+}
+
+func (t *Texture) rowSample(y, x0, x1 int, x float64) float64 {
+	i := y * t.W
+	return lerp(t.Pix[i+x0], t.Pix[i+x1], x)
 }
 
 func (t *Texture) SphericalSample(spherical Vector) float64 {
-	lat := math.Acos(spherical.Z)
-	lng := math.Atan2(spherical.Y, spherical.X)
-	u := (lng + math.Pi) / (2 * math.Pi)
-	v := lat / math.Pi
-	return t.BilinearSample(u, v)
+	u, v := t.project(spherical)
+	return t.Sample(u, v)
 }
 
 func (t *Texture) Displace(spherical Vector, lo, hi float64) Vector {
@@ -90,3 +326,40 @@ func gray16ToFloat64s(im *image.Gray16) []float64 {
 	}
 	return buf
 }
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+func clamp(x, lo, hi float64) float64 {
+	if x < lo {
+		return lo
+	}
+	if x > hi {
+		return hi
+	}
+	return x
+}
+
+func catmullRom(x float64) float64 {
+	x = math.Abs(x)
+	switch {
+	case x < 1:
+		return 1.5*x*x*x - 2.5*x*x + 1
+	case x < 2:
+		return -0.5*x*x*x + 2.5*x*x - 4*x + 2
+	}
+	return 0
+}
+
+func lanczos3(x float64) float64 {
+	x = math.Abs(x)
+	switch {
+	case x == 0:
+		return 1
+	case x < 3:
+		px := math.Pi * x
+		return 3 * math.Sin(px) * math.Sin(px/3) / (px * px)
+	}
+	return 0
+}
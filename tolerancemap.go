@@ -0,0 +1,80 @@
+package demsphere
+
+import "math"
+
+// ToleranceMap varies a Triangulator's tolerance and maximum detail level
+// over the sphere, to spend triangles where they matter most. Triangles are
+// given as unit directions.
+type ToleranceMap interface {
+	// Tolerance returns the tolerance for a triangle, given the global one.
+	Tolerance(v1, v2, v3 Vector, tolerance float64) float64
+
+	// MaxDetail returns the maximum detail level for a triangle, given the
+	// global one.
+	MaxDetail(v1, v2, v3 Vector, maxDetail int) int
+}
+
+// ToleranceRaster reads tolerances from a texture, such as one derived from
+// a DEM uncertainty layer, mapping texture values 0 to 1 onto Min to Max.
+// A triangle uses the smallest tolerance sampled at its corners and center.
+// Outside a regional texture the global tolerance applies.
+type ToleranceRaster struct {
+	Texture  *Texture
+	Min, Max float64
+}
+
+func (r *ToleranceRaster) Tolerance(v1, v2, v3 Vector, tolerance float64) float64 {
+	result := math.Inf(1)
+	center := v1.Add(v2).Add(v3).Normalize()
+	for _, v := range [4]Vector{v1, v2, v3, center} {
+		if r.Texture.Covers(v) {
+			result = math.Min(result, r.Min+r.Texture.SphericalSample(v)*(r.Max-r.Min))
+		}
+	}
+	if math.IsInf(result, 1) {
+		return tolerance
+	}
+	return result
+}
+
+func (r *ToleranceRaster) MaxDetail(v1, v2, v3 Vector, maxDetail int) int {
+	return maxDetail
+}
+
+// ToleranceRegion overrides the tolerance and, if MaxDetail is nonzero, the
+// maximum detail level within a region.
+type ToleranceRegion struct {
+	Region    *Region
+	Tolerance float64
+	MaxDetail int
+}
+
+// ToleranceRegions is a list of regions with their own tolerances. Where
+// regions overlap, the smallest tolerance and largest maximum detail apply.
+type ToleranceRegions []ToleranceRegion
+
+func (r ToleranceRegions) Tolerance(v1, v2, v3 Vector, tolerance float64) float64 {
+	result := math.Inf(1)
+	for _, region := range r {
+		if region.Region.classify(v1, v2, v3) != regionOutside {
+			result = math.Min(result, region.Tolerance)
+		}
+	}
+	if math.IsInf(result, 1) {
+		return tolerance
+	}
+	return result
+}
+
+func (r ToleranceRegions) MaxDetail(v1, v2, v3 Vector, maxDetail int) int {
+	result := -1
+	for _, region := range r {
+		if region.MaxDetail > 0 && region.Region.classify(v1, v2, v3) != regionOutside {
+			result = max(result, region.MaxDetail)
+		}
+	}
+	if result < 0 {
+		return maxDetail
+	}
+	return result
+}
@@ -2,136 +2,393 @@ package demsphere
 
 import (
 	"image"
+	"math"
+)
+
+// ToleranceSpace selects the units in which a Triangulator's tolerance is
+// given.
+type ToleranceSpace int
+
+const (
+	// SourceMeters measures the unexaggerated surface in meters.
+	SourceMeters ToleranceSpace = iota
+
+	// ExaggeratedMeters measures the surface in meters after exaggeration.
+	ExaggeratedMeters
+
+	// OutputUnits measures the final, exaggerated and scaled mesh, e.g. in
+	// millimeters on a print.
+	OutputUnits
 )
 
 type Triangulator struct {
-	texture *Texture
+	// Mipmap enables sampling the DEM from a mipmap pyramid, choosing the
+	// level from each triangle's angular footprint, so that coarse levels
+	// do not alias on sharp features.
+	Mipmap bool
+
+	// Region, if set, restricts the mesh to part of the sphere. Triangles
+	// straddling its boundary are refined to the maximum detail level and
+	// clipped, leaving a clean boundary loop. Relief triangulators ignore it.
+	Region *Region
+
+	// Ellipsoid, if set, replaces the sphere of the mean radius as the
+	// reference surface. Elevations are applied along its normal.
+	Ellipsoid *Ellipsoid
+
+	// Seed is the polyhedron subdivided at detail level zero. Relief and
+	// mesh triangulators ignore it.
+	Seed Seed
 
-	minDetail       int
-	maxDetail       int
-	minRadius       float64
-	maxRadius       float64
-	minOutputRadius float64
-	maxOutputRadius float64
-	tolerance       float64
+	// Refinement is the subdivision strategy.
+	Refinement Refinement
 
-	points map[Vector]Vector
-	counts map[int]int
+	// Metric measures the error of a triangle against the tolerance. See
+	// ErrorMetric.
+	Metric ErrorMetric
+
+	// ToleranceSpace is the space in which distances are compared against
+	// the tolerance.
+	ToleranceSpace ToleranceSpace
+
+	// ToleranceMap, if set, varies the tolerance and maximum detail level
+	// over the sphere.
+	ToleranceMap ToleranceMap
+
+	source ElevationSource
+	domain domain
+
+	minDetail    int
+	maxDetail    int
+	meanRadius   float64
+	tolerance    float64
+	exaggeration float64
+	scale        float64
+
+	points   map[Vector]Vector
+	details  map[Vector]int
+	counts   map[int]int
+	sampling *sampling
 
 	temp      []Triangle
+	boundary  []Triangle
 	triangles []Triangle
 }
 
 func NewTriangulator(im image.Image, minDetail, maxDetail int, meanRadius, minElevation, maxElevation, tolerance, exaggeration, scale float64) *Triangulator {
-	texture := NewTexture(im)
-	minRadius := meanRadius + minElevation
-	maxRadius := meanRadius + maxElevation
-	minOutputRadius := (meanRadius + minElevation*exaggeration) * scale
-	maxOutputRadius := (meanRadius + maxElevation*exaggeration) * scale
+	return NewTextureTriangulator(NewTexture(im), minDetail, maxDetail, meanRadius, minElevation, maxElevation, tolerance, exaggeration, scale)
+}
+
+func NewTextureTriangulator(texture *Texture, minDetail, maxDetail int, meanRadius, minElevation, maxElevation, tolerance, exaggeration, scale float64) *Triangulator {
+	source := &TextureSource{texture, minElevation, maxElevation}
+	return NewSourceTriangulator(source, minDetail, maxDetail, meanRadius, tolerance, exaggeration, scale)
+}
+
+func NewSourceTriangulator(source ElevationSource, minDetail, maxDetail int, meanRadius, tolerance, exaggeration, scale float64) *Triangulator {
 	points := make(map[Vector]Vector)
+	details := make(map[Vector]int)
 	counts := make(map[int]int)
-	return &Triangulator{texture, minDetail, maxDetail, minRadius, maxRadius, minOutputRadius, maxOutputRadius, tolerance, points, counts, nil, nil}
+	return &Triangulator{
+		source:       source,
+		domain:       sphere{meanRadius, nil, Icosahedron},
+		minDetail:    minDetail,
+		maxDetail:    maxDetail,
+		meanRadius:   meanRadius,
+		tolerance:    tolerance,
+		exaggeration: exaggeration,
+		scale:        scale,
+		points:       points,
+		details:      details,
+		counts:       counts,
+	}
+}
+
+// NewRadiusTriangulator returns a Triangulator for a shape model, whose
+// source gives the absolute distance from the body's center in meters in
+// each direction rather than an elevation above a reference surface. This is
+// how irregular small bodies are usually distributed. The body must be
+// star-shaped about its center, and Ellipsoid must not be set.
+func NewRadiusTriangulator(source ElevationSource, minDetail, maxDetail int, tolerance, scale float64) *Triangulator {
+	return NewSourceTriangulator(source, minDetail, maxDetail, 0, tolerance, 1, scale)
 }
 
 func (tri *Triangulator) Triangulate() []Triangle {
+	class := tri.prepare()
+	if tri.Refinement == EdgeBisection {
+		tri.triangulateBisection(class)
+	} else {
+		tri.triangulateSeeds(class)
+	}
+	tri.repair()
+	return tri.triangles
+}
+
+// triangulateSeeds refines the domain's seed quads, or its seed triangles if
+// it has none, by QuadSubdivision.
+func (tri *Triangulator) triangulateSeeds(class regionClass) {
+	if quads := tri.domain.quads(); quads != nil {
+		for _, q := range quads {
+			tri.triangulateQuad(0, class, q[0], q[1], q[2], q[3])
+		}
+		return
+	}
+	for _, t := range tri.domain.seed() {
+		tri.triangulate(0, class, t.A, t.B, t.C)
+	}
+}
+
+// prepare readies the sources and resets the Triangulator's state, returning
+// the region class of the seed triangles.
+func (tri *Triangulator) prepare() regionClass {
+	for _, t := range sourceTextures(tri.source) {
+		if tri.Mipmap && t.mipmaps == nil {
+			t.BuildMipmaps()
+		}
+	}
+	tri.configure()
+	tri.domain.reset()
+	tri.points = make(map[Vector]Vector)
+	tri.details = make(map[Vector]int)
+	tri.counts = make(map[int]int)
 	tri.temp = nil
+	tri.boundary = nil
 	tri.triangles = nil
-	for _, t := range NewIcosahedron() {
-		tri.triangulate(0, t.A, t.B, t.C)
+	if _, ok := tri.domain.(sphere); ok && tri.Region != nil {
+		return regionBoundary
+	}
+	return regionInside
+}
+
+// configure applies the exported options to the domain.
+func (tri *Triangulator) configure() {
+	if s, ok := tri.domain.(sphere); ok {
+		s.ellipsoid = tri.Ellipsoid
+		s.polyhedron = tri.Seed
+		tri.domain = s
 	}
-	// for d := 0; d <= tri.maxDetail; d++ {
-	// 	fmt.Println(d, tri.counts[d])
-	// }
+}
+
+// repair splits the accepted triangles to remove T-junctions, clipping those
+// on the region boundary, and emits the final mesh.
+func (tri *Triangulator) repair() {
 	for _, t := range tri.temp {
-		tri.split(t.A, t.B, t.C)
+		tri.split(false, t.A, t.B, t.C)
+		tri.domain.forgetSamples()
+	}
+	for _, t := range tri.boundary {
+		tri.split(true, t.A, t.B, t.C)
+		tri.domain.forgetSamples()
 	}
-	return tri.triangles
 }
 
-func (tri *Triangulator) split(v1, v2, v3 Vector) {
-	v12 := bisect(v1, v2)
-	v23 := bisect(v2, v3)
-	v31 := bisect(v3, v1)
+func (tri *Triangulator) split(clip bool, v1, v2, v3 Vector) {
+	v12 := tri.domain.sampleMidpoint(v1, v2)
+	v23 := tri.domain.sampleMidpoint(v2, v3)
+	v31 := tri.domain.sampleMidpoint(v3, v1)
 	if _, ok := tri.points[v12]; ok {
-		tri.split(v1, v12, v3)
-		tri.split(v12, v2, v3)
+		tri.split(clip, v1, v12, v3)
+		tri.split(clip, v12, v2, v3)
 	} else if _, ok := tri.points[v23]; ok {
-		tri.split(v1, v2, v23)
-		tri.split(v23, v3, v1)
+		tri.split(clip, v1, v2, v23)
+		tri.split(clip, v23, v3, v1)
 	} else if _, ok := tri.points[v31]; ok {
-		tri.split(v1, v2, v31)
-		tri.split(v31, v2, v3)
+		tri.split(clip, v1, v2, v31)
+		tri.split(clip, v31, v2, v3)
+	} else if clip {
+		for _, t := range tri.clip(v1, v2, v3) {
+			tri.emit(t[0], t[1], t[2])
+		}
 	} else {
-		p1 := tri.points[v1]
-		p2 := tri.points[v2]
-		p3 := tri.points[v3]
-		tri.triangles = append(tri.triangles, Triangle{p1, p2, p3})
+		tri.emit(v1, v2, v3)
 	}
 }
 
-func (tri *Triangulator) triangulate(detail int, v1, v2, v3 Vector) {
-	if detail == tri.maxDetail {
-		tri.leaf(v1, v2, v3)
-		tri.counts[detail]++
-		return
+// classify judges a triangle against the Region by the directions of its
+// keys, as ToleranceRegions do, so that both agree on an ellipsoid.
+func (tri *Triangulator) classify(v1, v2, v3 Vector) regionClass {
+	d := tri.domain
+	return tri.Region.classify(d.direction(v1), d.direction(v2), d.direction(v3))
+}
+
+// clip returns the parts of a triangle inside the Region. The corners keep
+// their keys, and the vertices made by clipping are keyed from their
+// directions.
+func (tri *Triangulator) clip(v1, v2, v3 Vector) [][3]Vector {
+	s := tri.domain.(sphere)
+	d1, d2, d3 := s.direction(v1), s.direction(v2), s.direction(v3)
+	var result [][3]Vector
+	for _, t := range tri.Region.clip(d1, d2, d3) {
+		for i, d := range t {
+			switch d {
+			case d1:
+				t[i] = v1
+			case d2:
+				t[i] = v2
+			case d3:
+				t[i] = v3
+			default:
+				t[i] = s.key(d)
+			}
+		}
+		result = append(result, t)
 	}
+	return result
+}
 
-	v12 := bisect(v1, v2)
-	v23 := bisect(v2, v3)
-	v31 := bisect(v3, v1)
+func (tri *Triangulator) emit(v1, v2, v3 Vector) {
+	var p [3]Vector
+	for i, v := range [3]Vector{v1, v2, v3} {
+		var ok bool
+		if p[i], ok = tri.points[v]; !ok {
+			p[i] = tri.output(v, tri.maxDetail)
+			tri.points[v] = p[i]
+		}
+	}
+	tri.triangles = append(tri.triangles, Triangle{p[0], p[1], p[2]})
+}
+
+// maxDetailFor returns the deepest detail level that a triangle may be
+// refined to.
+func (tri *Triangulator) maxDetailFor(v1, v2, v3 Vector) int {
+	d := tri.domain
+	maxDetail := tri.maxDetail
+	if s, ok := tri.source.(DetailSource); ok {
+		maxDetail = max(maxDetail, s.MaxDetail(d.direction(v1), d.direction(v2), d.direction(v3)))
+	}
+	if tri.ToleranceMap != nil {
+		maxDetail = tri.ToleranceMap.MaxDetail(d.direction(v1), d.direction(v2), d.direction(v3), maxDetail)
+	}
+	return maxDetail
+}
 
-	if detail >= tri.minDetail {
-		p1 := tri.texture.Displace(v1, tri.minRadius, tri.maxRadius)
-		p2 := tri.texture.Displace(v2, tri.minRadius, tri.maxRadius)
-		p3 := tri.texture.Displace(v3, tri.minRadius, tri.maxRadius)
-		plane := MakePlane(p1, p2, p3)
-		depth := tri.maxDetail - detail + 1
-		if depth > 5 {
-			depth = 5
+// toleranceFor returns the tolerance that applies to a triangle.
+func (tri *Triangulator) toleranceFor(v1, v2, v3 Vector) float64 {
+	if tri.ToleranceMap == nil {
+		return tri.tolerance
+	}
+	d := tri.domain
+	return tri.ToleranceMap.Tolerance(d.direction(v1), d.direction(v2), d.direction(v3), tri.tolerance)
+}
+
+func (tri *Triangulator) triangulate(detail int, class regionClass, v1, v2, v3 Vector) {
+	if class == regionBoundary {
+		class = tri.classify(v1, v2, v3)
+		if class == regionOutside {
+			return
 		}
-		if tri.withinTolerance(depth, plane, v1, v2, v3) {
-			tri.leaf(v1, v2, v3)
+	}
+
+	if tri.sampling != nil && detail == tri.sampling.detail && !tri.sampling.take() {
+		return
+	}
+
+	maxDetail := tri.maxDetailFor(v1, v2, v3)
+	if detail >= maxDetail {
+		tri.leaf(detail, class == regionBoundary, v1, v2, v3)
+		tri.counts[detail]++
+		return
+	}
+
+	if detail >= tri.minDetail && class != regionBoundary {
+		if tri.accurate(detail, maxDetail, v1, v2, v3) {
+			tri.leaf(detail, false, v1, v2, v3)
 			tri.counts[detail]++
 			return
 		}
 	}
 
-	tri.triangulate(detail+1, v1, v12, v31)
-	tri.triangulate(detail+1, v2, v23, v12)
-	tri.triangulate(detail+1, v3, v31, v23)
-	tri.triangulate(detail+1, v12, v23, v31)
+	v12 := tri.domain.midpoint(v1, v2)
+	v23 := tri.domain.midpoint(v2, v3)
+	v31 := tri.domain.midpoint(v3, v1)
+
+	tri.triangulate(detail+1, class, v1, v12, v31)
+	tri.triangulate(detail+1, class, v2, v23, v12)
+	tri.triangulate(detail+1, class, v3, v31, v23)
+	tri.triangulate(detail+1, class, v12, v23, v31)
+}
+
+func (tri *Triangulator) leaf(detail int, boundary bool, v1, v2, v3 Vector) {
+	for _, v := range [3]Vector{v1, v2, v3} {
+		if d, ok := tri.details[v]; !ok || detail > d {
+			tri.details[v] = detail
+			tri.points[v] = tri.output(v, detail)
+		}
+	}
+	if boundary {
+		tri.boundary = append(tri.boundary, Triangle{v1, v2, v3})
+	} else {
+		tri.temp = append(tri.temp, Triangle{v1, v2, v3})
+	}
+}
+
+// elevation samples the source at v, over the footprint of a triangle at the
+// given detail level when mipmapping.
+func (tri *Triangulator) elevation(v Vector, detail int) float64 {
+	var footprint float64
+	if tri.Mipmap {
+		footprint = tri.domain.footprint(detail)
+	}
+	return tri.source.Elevation(tri.domain.direction(v), footprint)
+}
+
+// surface returns the surface point above v in the tolerance space.
+func (tri *Triangulator) surface(v Vector, detail int) Vector {
+	return tri.toleranceSpace(v, tri.elevation(v, detail))
+}
+
+func (tri *Triangulator) toleranceSpace(v Vector, elevation float64) Vector {
+	switch tri.ToleranceSpace {
+	case ExaggeratedMeters:
+		return tri.domain.point(v, elevation*tri.exaggeration)
+	case OutputUnits:
+		return tri.domain.point(v, elevation*tri.exaggeration).MulScalar(tri.scale)
+	}
+	return tri.domain.point(v, elevation)
+}
+
+// output returns the exaggerated and scaled surface point above v.
+func (tri *Triangulator) output(v Vector, detail int) Vector {
+	e := tri.elevation(v, detail)
+	return tri.domain.point(v, e*tri.exaggeration).MulScalar(tri.scale)
 }
 
-func (tri *Triangulator) leaf(v1, v2, v3 Vector) {
-	p1 := tri.texture.Displace(v1, tri.minOutputRadius, tri.maxOutputRadius)
-	p2 := tri.texture.Displace(v2, tri.minOutputRadius, tri.maxOutputRadius)
-	p3 := tri.texture.Displace(v3, tri.minOutputRadius, tri.maxOutputRadius)
-	tri.points[v1] = p1
-	tri.points[v2] = p2
-	tri.points[v3] = p3
-	tri.temp = append(tri.temp, Triangle{v1, v2, v3})
+// accurate reports whether a triangle at a detail level approximates the
+// surface within the tolerance, sampling it down to at most five levels
+// finer or to the maximum detail level.
+func (tri *Triangulator) accurate(detail, maxDetail int, v1, v2, v3 Vector) bool {
+	defer tri.domain.forgetSamples()
+	depth := min(maxDetail-detail+1, 5)
+	tolerance := tri.toleranceFor(v1, v2, v3)
+	if tri.Metric != nil {
+		return tri.sampleError(detail, depth, v1, v2, v3) <= tolerance
+	}
+	p1 := tri.surface(v1, detail)
+	p2 := tri.surface(v2, detail)
+	p3 := tri.surface(v3, detail)
+	plane := MakePlane(p1, p2, p3)
+	return tri.withinTolerance(detail, depth, tolerance, plane, v1, v2, v3)
 }
 
-func (tri *Triangulator) withinTolerance(depth int, plane Plane, v1, v2, v3 Vector) bool {
+func (tri *Triangulator) withinTolerance(detail, depth int, tolerance float64, plane Plane, v1, v2, v3 Vector) bool {
 	if depth == 0 {
 		return true
 	}
 
-	v12 := bisect(v1, v2)
-	p12 := tri.texture.Displace(v12, tri.minRadius, tri.maxRadius)
-	if plane.DistanceToPoint(p12) > tri.tolerance {
+	v12 := tri.domain.sampleMidpoint(v1, v2)
+	p12 := tri.surface(v12, detail)
+	if plane.DistanceToPoint(p12) > tolerance {
 		return false
 	}
 
-	v23 := bisect(v2, v3)
-	p23 := tri.texture.Displace(v23, tri.minRadius, tri.maxRadius)
-	if plane.DistanceToPoint(p23) > tri.tolerance {
+	v23 := tri.domain.sampleMidpoint(v2, v3)
+	p23 := tri.surface(v23, detail)
+	if plane.DistanceToPoint(p23) > tolerance {
 		return false
 	}
 
-	v31 := bisect(v3, v1)
-	p13 := tri.texture.Displace(v31, tri.minRadius, tri.maxRadius)
-	if plane.DistanceToPoint(p13) > tri.tolerance {
+	v31 := tri.domain.sampleMidpoint(v3, v1)
+	p13 := tri.surface(v31, detail)
+	if plane.DistanceToPoint(p13) > tolerance {
 		return false
 	}
 
@@ -139,8 +396,123 @@ func (tri *Triangulator) withinTolerance(depth int, plane Plane, v1, v2, v3 Vect
 		return true
 	}
 
-	return tri.withinTolerance(depth-1, plane, v1, v12, v31) &&
-		tri.withinTolerance(depth-1, plane, v2, v23, v12) &&
-		tri.withinTolerance(depth-1, plane, v3, v31, v23) &&
-		tri.withinTolerance(depth-1, plane, v12, v23, v31)
+	return tri.withinTolerance(detail, depth-1, tolerance, plane, v1, v12, v31) &&
+		tri.withinTolerance(detail, depth-1, tolerance, plane, v2, v23, v12) &&
+		tri.withinTolerance(detail, depth-1, tolerance, plane, v3, v31, v23) &&
+		tri.withinTolerance(detail, depth-1, tolerance, plane, v12, v23, v31)
+}
+
+// domain is the surface that a Triangulator subdivides. Vertices are
+// identified by keys, which must be computed identically by every triangle
+// that shares them.
+type domain interface {
+	// seed returns the triangles at detail level zero.
+	seed() []Triangle
+
+	// quads returns the quads at detail level zero of a domain refined as
+	// quads, or nil to refine the triangles of seed.
+	quads() [][4]Vector
+
+	// midpoint returns the key halfway between two keys.
+	midpoint(a, b Vector) Vector
+
+	// sampleMidpoint is midpoint for a point that is only sampled, which the
+	// domain need not keep past forgetSamples.
+	sampleMidpoint(a, b Vector) Vector
+
+	// forgetSamples drops what the domain keeps for sampled points.
+	forgetSamples()
+
+	// reset drops what the domain keeps from a previous triangulation.
+	reset()
+
+	// direction returns the unit direction at which to sample the DEM.
+	direction(v Vector) Vector
+
+	// point returns the point at the given elevation above a key, in meters.
+	point(v Vector, elevation float64) Vector
+
+	// footprint returns the angular size, in radians, of a triangle at the
+	// given detail level.
+	footprint(detail int) float64
+}
+
+// sphere is the default domain, keyed by unit directions from the center of
+// a sphere or an ellipsoid. A cube-sphere is keyed by points on the surface
+// of the cube [-1, 1]³ instead, with equiangular coordinates on each face, so
+// that midpoints stay on their face.
+type sphere struct {
+	radius     float64
+	ellipsoid  *Ellipsoid
+	polyhedron Seed
+}
+
+func (s sphere) seed() []Triangle {
+	if s.polyhedron == CubeSphere {
+		var triangles []Triangle
+		for _, q := range cubeFaces() {
+			triangles = append(triangles, Triangle{q[0], q[1], q[2]}, Triangle{q[0], q[2], q[3]})
+		}
+		return triangles
+	}
+	return s.polyhedron.Triangles()
+}
+
+func (s sphere) quads() [][4]Vector {
+	if s.polyhedron == CubeSphere {
+		return cubeFaces()
+	}
+	return nil
+}
+
+func (s sphere) midpoint(a, b Vector) Vector {
+	if s.polyhedron == CubeSphere {
+		return Vector{(a.X + b.X) / 2, (a.Y + b.Y) / 2, (a.Z + b.Z) / 2}
+	}
+	return bisect(a, b)
+}
+
+func (s sphere) sampleMidpoint(a, b Vector) Vector {
+	return s.midpoint(a, b)
+}
+
+func (s sphere) forgetSamples() {}
+
+func (s sphere) reset() {}
+
+// unit returns the unit direction of a key.
+func (s sphere) unit(v Vector) Vector {
+	if s.polyhedron == CubeSphere {
+		return cubeToSphere(v)
+	}
+	return v
+}
+
+func (s sphere) direction(v Vector) Vector {
+	if s.ellipsoid != nil {
+		return s.ellipsoid.direction(s.unit(v))
+	}
+	return s.unit(v)
+}
+
+// key is the inverse of direction.
+func (s sphere) key(direction Vector) Vector {
+	if s.ellipsoid != nil {
+		direction = s.ellipsoid.key(direction)
+	}
+	if s.polyhedron == CubeSphere {
+		return sphereToCube(direction)
+	}
+	return direction
+}
+
+func (s sphere) point(v Vector, elevation float64) Vector {
+	if s.ellipsoid != nil {
+		return s.ellipsoid.point(s.unit(v), elevation)
+	}
+	return s.unit(v).MulScalar(s.radius + elevation)
+}
+
+func (s sphere) footprint(detail int) float64 {
+	return math.Ldexp(s.polyhedron.edge(), -detail)
 }
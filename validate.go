@@ -0,0 +1,288 @@
+package demsphere
+
+import "math"
+
+// maxValidationExamples limits the examples kept for each kind of defect.
+const maxValidationExamples = 100
+
+// ValidationDefect counts one kind of defect in a mesh, with examples of
+// the triangles involved in each.
+type ValidationDefect struct {
+	Count    int          `json:"count"`
+	Examples [][]Triangle `json:"examples,omitempty"`
+}
+
+func (d *ValidationDefect) add(triangles ...Triangle) {
+	d.Count++
+	if len(d.Examples) < maxValidationExamples {
+		d.Examples = append(d.Examples, triangles)
+	}
+}
+
+// ValidationReport describes the defects that would stop a mesh from being
+// a watertight, manifold, consistently oriented solid.
+type ValidationReport struct {
+	Triangles int `json:"triangles"`
+	Vertices  int `json:"vertices"`
+	Shells    int `json:"shells"`
+
+	// BoundaryEdges are used by only one triangle, such as the long edge
+	// of an unrepaired T-junction.
+	BoundaryEdges ValidationDefect `json:"boundary_edges"`
+
+	// NonManifoldEdges are shared by more than two triangles.
+	NonManifoldEdges ValidationDefect `json:"non_manifold_edges"`
+
+	// InconsistentEdges are shared by two triangles that traverse them in
+	// the same direction, so that one of them faces the wrong way.
+	InconsistentEdges ValidationDefect `json:"inconsistent_edges"`
+
+	// Degenerate triangles have no area.
+	Degenerate ValidationDefect `json:"degenerate_triangles"`
+
+	// Duplicate triangles have the same vertices as an earlier one.
+	Duplicate ValidationDefect `json:"duplicate_triangles"`
+
+	// Intersections are pairs of triangles in different shells that cross.
+	Intersections ValidationDefect `json:"shell_intersections"`
+}
+
+// Valid reports whether the mesh has no defects.
+func (r *ValidationReport) Valid() bool {
+	return r.BoundaryEdges.Count == 0 && r.NonManifoldEdges.Count == 0 &&
+		r.InconsistentEdges.Count == 0 && r.Degenerate.Count == 0 &&
+		r.Duplicate.Count == 0 && r.Intersections.Count == 0
+}
+
+// edgeUse records the triangles that use an undirected edge, and how many of
+// them traverse it from its lesser vertex.
+type edgeUse struct {
+	count    int
+	first    int
+	second   int
+	forwards int
+}
+
+// Validate checks that a mesh is watertight, manifold and consistently
+// wound, without degenerate or duplicate triangles, and that its separate
+// shells, such as the outer and inner shells of a globe, do not intersect.
+// Vertices are matched exactly.
+func Validate(triangles []Triangle) *ValidationReport {
+	r := &ValidationReport{Triangles: len(triangles)}
+
+	vertices := make(map[Vector]bool)
+	seen := make(map[[3]Vector]int)
+	shells := newUnionFind(len(triangles))
+	edges := make(map[[2]Vector]*edgeUse)
+	for i, t := range triangles {
+		vs := [3]Vector{t.A, t.B, t.C}
+		for _, v := range vs {
+			vertices[v] = true
+		}
+
+		e1, e2 := t.B.Sub(t.A), t.C.Sub(t.A)
+		longest := math.Max(e1.Length(), math.Max(e2.Length(), t.C.Sub(t.B).Length()))
+		if e1.Cross(e2).Length() <= 1e-12*longest*longest {
+			r.Degenerate.add(t)
+		}
+
+		key := vs
+		for a := 0; a < 3; a++ {
+			for b := a + 1; b < 3; b++ {
+				if vectorLess(key[b], key[a]) {
+					key[a], key[b] = key[b], key[a]
+				}
+			}
+		}
+		if j, ok := seen[key]; ok {
+			r.Duplicate.add(triangles[j], t)
+		} else {
+			seen[key] = i
+		}
+
+		for k := 0; k < 3; k++ {
+			a, b := vs[k], vs[(k+1)%3]
+			if a == b {
+				continue
+			}
+			forward := 1
+			if vectorLess(b, a) {
+				a, b = b, a
+				forward = 0
+			}
+			e, ok := edges[[2]Vector{a, b}]
+			if !ok {
+				e = &edgeUse{first: i}
+				edges[[2]Vector{a, b}] = e
+			}
+			e.count++
+			e.forwards += forward
+			shells.union(e.first, i)
+			switch e.count {
+			case 2:
+				e.second = i
+			case 3:
+				r.NonManifoldEdges.add(triangles[e.first], triangles[e.second], t)
+			}
+		}
+	}
+	r.Vertices = len(vertices)
+
+	for _, e := range edges {
+		switch {
+		case e.count == 1:
+			r.BoundaryEdges.add(triangles[e.first])
+		case e.count == 2 && e.forwards != 1:
+			r.InconsistentEdges.add(triangles[e.first], triangles[e.second])
+		}
+	}
+	shell := make([]int, len(triangles))
+	roots := make(map[int]bool)
+	for i := range triangles {
+		shell[i] = shells.find(i)
+		roots[shell[i]] = true
+	}
+	r.Shells = len(roots)
+	if r.Shells > 1 {
+		validateIntersections(r, triangles, shell)
+	}
+	return r
+}
+
+// validateIntersections finds crossing triangles in different shells, using
+// a uniform grid of cells about as large as the mean edge.
+func validateIntersections(r *ValidationReport, triangles []Triangle, shell []int) {
+	var size float64
+	for _, t := range triangles {
+		size += t.B.Sub(t.A).Length() + t.C.Sub(t.B).Length() + t.A.Sub(t.C).Length()
+	}
+	size /= 3 * float64(len(triangles))
+	if size <= 0 {
+		return
+	}
+	cell := func(x float64) int {
+		return int(math.Floor(x / size))
+	}
+	type box struct{ min, max [3]int }
+	bounds := func(t Triangle) box {
+		var b box
+		for k, f := range [3]func(Vector) float64{
+			func(v Vector) float64 { return v.X },
+			func(v Vector) float64 { return v.Y },
+			func(v Vector) float64 { return v.Z },
+		} {
+			lo := math.Min(f(t.A), math.Min(f(t.B), f(t.C)))
+			hi := math.Max(f(t.A), math.Max(f(t.B), f(t.C)))
+			b.min[k], b.max[k] = cell(lo), cell(hi)
+		}
+		return b
+	}
+	grid := make(map[[3]int][]int)
+	boxes := make([]box, len(triangles))
+	for i, t := range triangles {
+		b := bounds(t)
+		boxes[i] = b
+		for x := b.min[0]; x <= b.max[0]; x++ {
+			for y := b.min[1]; y <= b.max[1]; y++ {
+				for z := b.min[2]; z <= b.max[2]; z++ {
+					k := [3]int{x, y, z}
+					grid[k] = append(grid[k], i)
+				}
+			}
+		}
+	}
+	for k, cellTriangles := range grid {
+		for m, i := range cellTriangles {
+			for _, j := range cellTriangles[m+1:] {
+				if shell[i] == shell[j] {
+					continue
+				}
+				// only test each pair in the first cell that they share
+				bi, bj := boxes[i], boxes[j]
+				first := true
+				for a := 0; a < 3; a++ {
+					lo := max(bi.min[a], bj.min[a])
+					if lo > min(bi.max[a], bj.max[a]) {
+						first = false
+						break
+					}
+					if k[a] != lo {
+						first = false
+					}
+				}
+				if first && trianglesIntersect(triangles[i], triangles[j]) {
+					r.Intersections.add(triangles[i], triangles[j])
+				}
+			}
+		}
+	}
+}
+
+// trianglesIntersect reports whether an edge of either triangle crosses the
+// other. Triangles that share a vertex only touch, and coplanar overlaps are
+// not detected.
+func trianglesIntersect(s, t Triangle) bool {
+	for _, v := range [3]Vector{s.A, s.B, s.C} {
+		if v == t.A || v == t.B || v == t.C {
+			return false
+		}
+	}
+	for _, p := range [2][2]Triangle{{s, t}, {t, s}} {
+		a, b := p[0], p[1]
+		for _, e := range [3][2]Vector{{a.A, a.B}, {a.B, a.C}, {a.C, a.A}} {
+			if segmentIntersects(e[0], e[1], b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentIntersects reports whether the segment p-q crosses a triangle,
+// using the Möller-Trumbore test.
+func segmentIntersects(p, q Vector, t Triangle) bool {
+	const eps = 1e-12
+	d := q.Sub(p)
+	e1 := t.B.Sub(t.A)
+	e2 := t.C.Sub(t.A)
+	h := d.Cross(e2)
+	a := e1.Dot(h)
+	if math.Abs(a) < eps*e1.Length()*e2.Length()*d.Length() {
+		return false
+	}
+	f := 1 / a
+	s := p.Sub(t.A)
+	u := f * s.Dot(h)
+	if u < 0 || u > 1 {
+		return false
+	}
+	c := s.Cross(e1)
+	v := f * d.Dot(c)
+	if v < 0 || u+v > 1 {
+		return false
+	}
+	x := f * e2.Dot(c)
+	return x >= 0 && x <= 1
+}
+
+type unionFind []int
+
+func newUnionFind(n int) unionFind {
+	u := make(unionFind, n)
+	for i := range u {
+		u[i] = i
+	}
+	return u
+}
+
+func (u unionFind) find(i int) int {
+	for u[i] != i {
+		u[i] = u[u[i]]
+		i = u[i]
+	}
+	return i
+}
+
+func (u unionFind) union(i, j int) {
+	u[u.find(i)] = u.find(j)
+}
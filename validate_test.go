@@ -0,0 +1,74 @@
+package demsphere
+
+import "testing"
+
+// testTetrahedron returns a closed, outward-facing tetrahedron.
+func testTetrahedron(scale float64, offset Vector) []Triangle {
+	a := Vector{1, 1, 1}.MulScalar(scale).Add(offset)
+	b := Vector{1, -1, -1}.MulScalar(scale).Add(offset)
+	c := Vector{-1, 1, -1}.MulScalar(scale).Add(offset)
+	d := Vector{-1, -1, 1}.MulScalar(scale).Add(offset)
+	return []Triangle{{a, b, c}, {a, c, d}, {a, d, b}, {b, d, c}}
+}
+
+func TestValidate(t *testing.T) {
+	tetrahedron := testTetrahedron(1, Vector{})
+	flipped := append([]Triangle{}, tetrahedron...)
+	flipped[0] = Triangle{flipped[0].C, flipped[0].B, flipped[0].A}
+	degenerate := Triangle{Vector{0, 0, 0}, Vector{1, 0, 0}, Vector{2, 0, 0}}
+
+	tests := []struct {
+		name      string
+		triangles []Triangle
+		want      ValidationReport
+		cross     bool
+	}{
+		{"closed", tetrahedron, ValidationReport{Triangles: 4, Vertices: 4, Shells: 1}, false},
+		{"open", tetrahedron[1:], ValidationReport{Triangles: 3, Vertices: 4, Shells: 1,
+			BoundaryEdges: ValidationDefect{Count: 3}}, false},
+		{"flipped", flipped, ValidationReport{Triangles: 4, Vertices: 4, Shells: 1,
+			InconsistentEdges: ValidationDefect{Count: 3}}, false},
+		{"duplicate", append(tetrahedron, tetrahedron[0]), ValidationReport{Triangles: 5, Vertices: 4, Shells: 1,
+			NonManifoldEdges: ValidationDefect{Count: 3}, Duplicate: ValidationDefect{Count: 1}}, false},
+		{"degenerate", []Triangle{degenerate}, ValidationReport{Triangles: 1, Vertices: 3, Shells: 1,
+			BoundaryEdges: ValidationDefect{Count: 3}, Degenerate: ValidationDefect{Count: 1}}, false},
+		{"nested", append(testTetrahedron(1, Vector{}), testTetrahedron(0.5, Vector{})...),
+			ValidationReport{Triangles: 8, Vertices: 8, Shells: 2}, false},
+		{"crossing", append(testTetrahedron(1, Vector{}), testTetrahedron(1, Vector{0.5, 0, 0})...),
+			ValidationReport{Triangles: 8, Vertices: 8, Shells: 2}, true},
+	}
+	for _, test := range tests {
+		r := Validate(test.triangles)
+		got := []int{r.Triangles, r.Vertices, r.Shells, r.BoundaryEdges.Count, r.NonManifoldEdges.Count,
+			r.InconsistentEdges.Count, r.Degenerate.Count, r.Duplicate.Count}
+		w := test.want
+		want := []int{w.Triangles, w.Vertices, w.Shells, w.BoundaryEdges.Count, w.NonManifoldEdges.Count,
+			w.InconsistentEdges.Count, w.Degenerate.Count, w.Duplicate.Count}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: got %v, want %v", test.name, got, want)
+				break
+			}
+		}
+		if (r.Intersections.Count > 0) != test.cross {
+			t.Errorf("%s: got %d shell intersections", test.name, r.Intersections.Count)
+		}
+		valid := w.BoundaryEdges.Count+w.NonManifoldEdges.Count+w.InconsistentEdges.Count+
+			w.Degenerate.Count+w.Duplicate.Count == 0 && !test.cross
+		if r.Valid() != valid {
+			t.Errorf("%s: Valid() = %v", test.name, r.Valid())
+		}
+	}
+}
+
+// Examples are kept for the first defects only.
+func TestValidateExamples(t *testing.T) {
+	var triangles []Triangle
+	for i := 0; i < maxValidationExamples+10; i++ {
+		triangles = append(triangles, testTetrahedron(1, Vector{X: 10 * float64(i)})[0])
+	}
+	r := Validate(triangles)
+	if r.BoundaryEdges.Count != 3*len(triangles) || len(r.BoundaryEdges.Examples) != maxValidationExamples {
+		t.Errorf("got %d boundary edges with %d examples", r.BoundaryEdges.Count, len(r.BoundaryEdges.Examples))
+	}
+}
@@ -11,6 +11,11 @@ func bisect(v1, v2 Vector) Vector {
 	return Vector{x * r, y * r, z * r}
 }
 
+// angle returns the angle, in radians, between two unit vectors.
+func angle(a, b Vector) float64 {
+	return 2 * math.Asin(math.Min(a.Sub(b).Length()/2, 1))
+}
+
 type Vector struct {
 	X, Y, Z float64
 }
@@ -34,6 +39,10 @@ func (a Vector) Dot(b Vector) float64 {
 	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
 }
 
+func (a Vector) Length() float64 {
+	return math.Sqrt(a.X*a.X + a.Y*a.Y + a.Z*a.Z)
+}
+
 func (a Vector) MulScalar(b float64) Vector {
 	return Vector{a.X * b, a.Y * b, a.Z * b}
 }